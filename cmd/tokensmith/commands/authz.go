@@ -25,6 +25,10 @@ var (
 	authzAddr              string
 	authzPort              int
 	workloadKubeconfig     string
+	workloadAudiences      []string
+	legacyTokenMaxAge      time.Duration
+	reviewCacheTTL         time.Duration
+	reviewNegativeCacheTTL time.Duration
 	clustersConfig         string
 	tokenExpirationSeconds int64
 )
@@ -45,6 +49,14 @@ for tokens in the management cluster using Kubernetes TokenReview and TokenReque
 	cmd.Flags().IntVar(&authzPort, "port", 9001, "Server port")
 	cmd.Flags().StringVar(&workloadKubeconfig, "workload-kubeconfig", "",
 		"Path to kubeconfig for workload cluster (deprecated: use --clusters-config instead)")
+	cmd.Flags().StringSliceVar(&workloadAudiences, "workload-audiences", nil,
+		"Audiences required in workload cluster tokens when using --workload-kubeconfig")
+	cmd.Flags().DurationVar(&legacyTokenMaxAge, "legacy-token-max-age", 0,
+		"Accept legacy Secret-based tokens created within this duration when using --workload-kubeconfig (default: reject)")
+	cmd.Flags().DurationVar(&reviewCacheTTL, "tokenreview-cache-ttl", 30*time.Second,
+		"Maximum time to cache successful TokenReview results, capped by token expiry (0 disables)")
+	cmd.Flags().DurationVar(&reviewNegativeCacheTTL, "tokenreview-negative-cache-ttl", 5*time.Second,
+		"Time to cache TokenReview rejections (0 disables)")
 	cmd.Flags().StringVar(&clustersConfig, "clusters-config", "",
 		"Path to YAML file containing multi-cluster configuration")
 	cmd.Flags().Int64Var(&tokenExpirationSeconds, "token-expiration", 3600,
@@ -54,20 +66,28 @@ for tokens in the management cluster using Kubernetes TokenReview and TokenReque
 }
 
 func runAuthz(cmd *cobra.Command, args []string) error {
-	ctx := context.Background()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	logger := slog.Default()
 
 	logger.Info("initializing external authorization server",
 		slog.String("addr", authzAddr),
 		slog.Int("port", authzPort),
 		slog.String("workload_kubeconfig", workloadKubeconfig),
+		slog.Any("workload_audiences", workloadAudiences),
+		slog.Duration("legacy_token_max_age", legacyTokenMaxAge),
+		slog.Duration("tokenreview_cache_ttl", reviewCacheTTL),
+		slog.Duration("tokenreview_negative_cache_ttl", reviewNegativeCacheTTL),
 		slog.String("clusters_config", clustersConfig),
 		slog.Int64("token_expiration", tokenExpirationSeconds),
 	)
 
 	// Determine which validation mode to use
 	var validator token.TokenValidator
+	var jwksValidator *token.JWKSValidator
 	var clients *token.Clients
+	var workloadClusters *config.ClustersConfig
+	managementAudiences := config.DefaultManagementAudiences
 
 	if clustersConfig != "" {
 		// Use JWKS-based multi-cluster validation
@@ -81,23 +101,86 @@ func runAuthz(cmd *cobra.Command, args []string) error {
 			slog.Int("num_clusters", len(cfg.Clusters)),
 		)
 
-		validator = token.NewJWKSValidator(cfg)
-
-		// Initialize management cluster client only
+		// Initialize management cluster client only; workload cluster clients
+		// are added for clusters with a kubeconfig
 		clientConfig := token.ClientConfig{
 			UseInClusterForManagement: true,
+			SkipWorkload:              true,
 		}
 		clients, err = token.NewClients(ctx, clientConfig)
 		if err != nil {
 			return fmt.Errorf("failed to create Kubernetes clients: %w", err)
 		}
 
-		// Health check management cluster only
-		logger.Info("performing management cluster health check")
-		if err := clients.Management.Discovery().RESTClient().Get().AbsPath("/healthz").Do(ctx).Error(); err != nil {
-			return fmt.Errorf("management cluster health check failed: %w", err)
+		// Load workload cluster kubeconfigs, which may be stored in management
+		// cluster Secrets
+		restConfigs, err := token.NewClusterRESTConfigs(ctx, cfg, clients.Management)
+		if err != nil {
+			return fmt.Errorf("failed to load workload cluster kubeconfigs: %w", err)
+		}
+		if err := clients.AddClusters(restConfigs); err != nil {
+			return err
+		}
+
+		// Health check the management cluster and each workload cluster with a
+		// kubeconfig. Only the management cluster is required; unreachable
+		// workload clusters fail on their own until they recover.
+		logger.Info("performing cluster health checks",
+			slog.Int("num_workload_clusters", len(clients.Clusters)),
+		)
+		if err := clients.HealthCheck(ctx); err != nil {
+			return fmt.Errorf("cluster health check failed: %w", err)
+		}
+		unreachable := clients.CheckClusters(ctx)
+		for name, err := range unreachable {
+			logger.Warn("workload cluster unreachable",
+				slog.String("cluster", name),
+				slog.String("error", err.Error()),
+			)
+		}
+		logger.Info("cluster health checks completed",
+			slog.Int("num_unreachable_workload_clusters", len(unreachable)),
+		)
+
+		jwksValidator = token.NewJWKSValidator(cfg)
+		managementAudiences = cfg.GetManagementAudiences()
+		workloadClusters = cfg
+
+		// Configure workload API server access for clusters with a kubeconfig
+		for name, restConfig := range restConfigs {
+			if err := jwksValidator.SetWorkloadRESTConfig(name, restConfig); err != nil {
+				return fmt.Errorf("failed to configure workload cluster %s: %w", name, err)
+			}
 		}
-		logger.Info("management cluster health check passed")
+
+		// Refresh remote key sets and watch service accounts in the background
+		jwksValidator.Start(ctx, logger)
+		// Clusters whose service accounts haven't synced reject tokens until
+		// their informers sync, so they don't block startup
+		syncCtx, syncCancel := context.WithTimeout(ctx, time.Minute)
+		unsynced := jwksValidator.WaitForServiceAccounts(syncCtx)
+		syncCancel()
+		for _, name := range unsynced {
+			logger.Warn("workload cluster service accounts not synced",
+				slog.String("cluster", name),
+			)
+		}
+
+		// Route tokens to JWKS or TokenReview validation by cluster
+		compositeValidator := token.NewCompositeValidator(cfg, jwksValidator)
+		for _, cluster := range cfg.Clusters {
+			if !cluster.UsesTokenReview() {
+				continue
+			}
+			reviewer := token.NewValidator(clients.Clusters[cluster.Name], cluster.Audiences)
+			if cluster.LegacyTokens != nil {
+				reviewer.AllowLegacyTokens(cluster.LegacyTokens.MaxAge)
+			}
+			reviewer.RejectManagementAudiences(cfg.GetExchangedAudiences())
+			reviewer.EnableCache(reviewCacheTTL, reviewNegativeCacheTTL)
+			compositeValidator.SetTokenReviewValidator(cluster.Name, reviewer)
+		}
+		validator = compositeValidator
 
 	} else {
 		// Use legacy TokenReview-based validation
@@ -125,16 +208,39 @@ func runAuthz(cmd *cobra.Command, args []string) error {
 		logger.Info("cluster health checks passed")
 
 		// Create token validator (workload cluster)
-		validator = token.NewValidator(clients.Workload)
+		tokenReviewValidator := token.NewValidator(clients.Workload, workloadAudiences)
+		tokenReviewValidator.AllowLegacyTokens(legacyTokenMaxAge)
+		// Workload tokens for the API server audience are common in single
+		// cluster mode, so only guard against exchange loops when the
+		// workload audiences are distinct
+		if len(workloadAudiences) > 0 {
+			tokenReviewValidator.RejectManagementAudiences(managementAudiences)
+		}
+		tokenReviewValidator.EnableCache(reviewCacheTTL, reviewNegativeCacheTTL)
+		validator = tokenReviewValidator
 	}
 
 	// Create token exchanger (management cluster)
 	exchangeConfig := token.ExchangeConfig{
-		Audiences:         []string{"https://kubernetes.default.svc"},
+		Audiences:         managementAudiences,
 		ExpirationSeconds: &tokenExpirationSeconds,
 	}
+	if workloadClusters != nil {
+		exchangeConfig.IdentityMappings = workloadClusters.IdentityMappings
+		exchangeConfig.Clusters = workloadClusters
+		exchangeConfig.RequireTrustedSubjects = workloadClusters.RequireTrustedSubjects
+		exchangeConfig.Profiles = workloadClusters.ExchangeProfiles
+	}
 	exchanger := token.NewExchanger(clients.Management, exchangeConfig)
 
+	// Evict cached tokens of workload service accounts that were deleted or
+	// recreated
+	if jwksValidator != nil {
+		jwksValidator.OnServiceAccountRemoved(func(cluster, uid string) {
+			exchanger.Evict(cluster, uid)
+		})
+	}
+
 	// Create ext_authz server
 	authzServer := authz.NewServer(validator, exchanger, logger)
 
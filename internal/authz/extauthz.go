@@ -2,6 +2,7 @@ package authz
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -50,25 +51,35 @@ func (s *Server) Check(ctx context.Context, req *envoy_auth.CheckRequest) (*envo
 	}
 
 	// Validate token using workload cluster TokenReview API
-	identity, err := s.validator.Validate(ctx, bearerToken)
+	identity, err := s.validator.Validate(token.WithSource(ctx, getSource(req)), bearerToken)
 	if err != nil {
 		s.logger.Warn("token validation failed",
 			slog.String("error", err.Error()),
 		)
-		return s.denyResponse(codes.Unauthenticated, "Token validation failed"), nil
+		return s.denyResponse(codes.Unauthenticated, validationDenyMessage(err)), nil
 	}
 
 	s.logger.Info("token validated successfully",
-		slog.String("namespace", identity.Namespace),
-		slog.String("service_account", identity.Name),
-		slog.String("uid", identity.UID),
+		identityLogAttrs(identity)...,
 	)
 
+	if identity.ReplaySource != nil {
+		s.logger.Warn("possible token replay",
+			slog.String("jti", identity.TokenID),
+			slog.String("first_source", identity.ReplaySource.String()),
+			slog.String("source", getSource(req).String()),
+			slog.String("cluster", identity.Cluster),
+			slog.String("namespace", identity.Namespace),
+			slog.String("service_account", identity.Name),
+		)
+	}
+
 	// Exchange for management cluster token
-	managementToken, err := s.exchanger.Exchange(ctx, identity)
+	exchanged, err := s.exchanger.ExchangeWithMetadata(ctx, identity)
 	if err != nil {
 		s.logger.Error("token exchange failed",
 			slog.String("error", err.Error()),
+			slog.String("cluster", identity.Cluster),
 			slog.String("namespace", identity.Namespace),
 			slog.String("service_account", identity.Name),
 		)
@@ -76,12 +87,61 @@ func (s *Server) Check(ctx context.Context, req *envoy_auth.CheckRequest) (*envo
 	}
 
 	s.logger.Info("token exchanged successfully",
+		slog.String("cluster", identity.Cluster),
 		slog.String("namespace", identity.Namespace),
 		slog.String("service_account", identity.Name),
+		slog.String("target_namespace", exchanged.Namespace),
+		slog.String("target_service_account", exchanged.ServiceAccount),
+		slog.String("profile", exchanged.Profile),
 	)
 
 	// Return OK response with modified Authorization header
-	return s.okResponseWithToken(managementToken), nil
+	return s.okResponseWithToken(exchanged.Token), nil
+}
+
+// identityLogAttrs returns the audit log attributes for a validated identity,
+// including any objects the token is bound to.
+func identityLogAttrs(identity *token.ServiceAccountIdentity) []any {
+	attrs := []any{
+		slog.String("cluster", identity.Cluster),
+		slog.String("namespace", identity.Namespace),
+		slog.String("service_account", identity.Name),
+		slog.String("uid", identity.UID),
+	}
+	if identity.Kind != "" {
+		attrs = append(attrs, slog.String("token_kind", string(identity.Kind)))
+	}
+	if identity.Pod != nil {
+		attrs = append(attrs, slog.Group("pod",
+			slog.String("name", identity.Pod.Name),
+			slog.String("uid", identity.Pod.UID),
+		))
+	}
+	if identity.Node != nil {
+		attrs = append(attrs, slog.Group("node",
+			slog.String("name", identity.Node.Name),
+			slog.String("uid", identity.Node.UID),
+		))
+	}
+	if identity.Secret != nil {
+		attrs = append(attrs, slog.Group("secret",
+			slog.String("name", identity.Secret.Name),
+			slog.String("uid", identity.Secret.UID),
+		))
+	}
+	return attrs
+}
+
+// validationDenyMessage returns the deny message for a token validation error.
+// Details are only logged, except for reasons the client must act on.
+func validationDenyMessage(err error) string {
+	if errors.Is(err, token.ErrLegacyToken) {
+		return "Legacy service account tokens are not accepted; use a projected service account token"
+	}
+	if errors.Is(err, token.ErrTokenReplayed) {
+		return "Token replay detected"
+	}
+	return "Token validation failed"
 }
 
 // extractBearerToken extracts the bearer token from the Authorization header.
@@ -119,6 +179,15 @@ func getPath(req *envoy_auth.CheckRequest) string {
 	return req.GetAttributes().GetRequest().GetHttp().GetPath()
 }
 
+// getSource returns the downstream peer the request came from.
+func getSource(req *envoy_auth.CheckRequest) token.Source {
+	source := req.GetAttributes().GetSource()
+	return token.Source{
+		Address:   source.GetAddress().GetSocketAddress().GetAddress(),
+		Principal: source.GetPrincipal(),
+	}
+}
+
 // getMethod extracts the HTTP method from the check request.
 func getMethod(req *envoy_auth.CheckRequest) string {
 	if req.GetAttributes() == nil || req.GetAttributes().GetRequest() == nil ||
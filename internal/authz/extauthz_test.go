@@ -1,10 +1,15 @@
 package authz
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 
+	envoy_core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
 	"google.golang.org/grpc/codes"
+
+	"github.com/holos-run/tokensmith/internal/token"
 )
 
 func TestExtractBearerToken(t *testing.T) {
@@ -181,6 +186,53 @@ func TestGetMethod(t *testing.T) {
 	}
 }
 
+func TestGetSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      *envoy_auth.CheckRequest
+		expected token.Source
+	}{
+		{
+			name: "peer with SPIFFE principal",
+			req: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Source: &envoy_auth.AttributeContext_Peer{
+						Address: &envoy_core.Address{
+							Address: &envoy_core.Address_SocketAddress{
+								SocketAddress: &envoy_core.SocketAddress{
+									Address: "10.0.0.1",
+									PortSpecifier: &envoy_core.SocketAddress_PortValue{
+										PortValue: 43210,
+									},
+								},
+							},
+						},
+						Principal: "spiffe://cluster.local/ns/default/sa/my-sa",
+					},
+				},
+			},
+			expected: token.Source{
+				Address:   "10.0.0.1",
+				Principal: "spiffe://cluster.local/ns/default/sa/my-sa",
+			},
+		},
+		{
+			name:     "nil request",
+			req:      &envoy_auth.CheckRequest{},
+			expected: token.Source{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := getSource(tt.req)
+			if result != tt.expected {
+				t.Errorf("source mismatch: got %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestHttpStatusFromGRPCCode(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -205,3 +257,36 @@ func TestHttpStatusFromGRPCCode(t *testing.T) {
 		})
 	}
 }
+
+func TestValidationDenyMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name:     "legacy token",
+			err:      fmt.Errorf("validation: %w", token.ErrLegacyToken),
+			expected: "Legacy service account tokens are not accepted; use a projected service account token",
+		},
+		{
+			name:     "replayed token",
+			err:      fmt.Errorf("validation: %w", token.ErrTokenReplayed),
+			expected: "Token replay detected",
+		},
+		{
+			name:     "other error",
+			err:      errors.New("signature invalid"),
+			expected: "Token validation failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := validationDenyMessage(tt.err)
+			if result != tt.expected {
+				t.Errorf("message mismatch: got %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
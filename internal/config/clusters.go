@@ -3,14 +3,103 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/go-jose/go-jose/v4"
 )
 
+// DefaultMaxStale is the default window during which the last good key set
+// is served after a JWKS refresh fails.
+const DefaultMaxStale = 24 * time.Hour
+
+// DefaultMaxResponseBytes is the default maximum size of a JWKS or discovery
+// document response.
+const DefaultMaxResponseBytes = 1 << 20
+
+// DefaultManagementAudiences is the default list of audiences for tokens
+// issued by the management cluster.
+var DefaultManagementAudiences = []string{"https://kubernetes.default.svc"}
+
+// SupportedSigningAlgorithms is the list of algorithms tokens may be signed
+// with. Symmetric algorithms are never accepted.
+var SupportedSigningAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256,
+	jose.RS384,
+	jose.RS512,
+	jose.PS256,
+	jose.PS384,
+	jose.PS512,
+	jose.ES256,
+	jose.ES384,
+	jose.ES512,
+	jose.EdDSA,
+}
+
+// SystemNamespaces are management cluster namespaces identities may only
+// exchange into when a cluster lists them by name in ManagementNamespaces.
+var SystemNamespaces = []string{"kube-system", "kube-public", "kube-node-lease"}
+
+// Issuer types.
+const (
+	// ClusterTypeKubernetes is a Kubernetes cluster issuing service account tokens.
+	ClusterTypeKubernetes = "kubernetes"
+
+	// ClusterTypeOIDC is an OIDC issuer such as a CI system or Dex.
+	ClusterTypeOIDC = "oidc"
+
+	// ClusterTypeSPIFFE is a SPIFFE trust domain issuing JWT-SVIDs.
+	ClusterTypeSPIFFE = "spiffe"
+)
+
+// DefaultSPIFFEPaths is the default list of SPIFFE ID path templates, matching
+// the IDs Istio assigns to Kubernetes service accounts.
+var DefaultSPIFFEPaths = []string{"/ns/{namespace}/sa/{name}"}
+
+// Validation modes.
+const (
+	// ValidationJWKS verifies token signatures locally with the cluster's JWKS.
+	ValidationJWKS = "jwks"
+
+	// ValidationTokenReview validates tokens with the TokenReview API of the
+	// cluster's API server.
+	ValidationTokenReview = "tokenreview"
+
+	// ValidationJWKSThenTokenReview verifies tokens with the JWKS and
+	// cross-checks them with the TokenReview API to detect revocation. If the
+	// keys are unavailable, the TokenReview result is authoritative.
+	ValidationJWKSThenTokenReview = "jwks-then-tokenreview"
+)
+
 // ClustersConfig contains configuration for multiple workload clusters.
 type ClustersConfig struct {
 	// Clusters is the list of workload cluster configurations.
 	Clusters []ClusterConfig `yaml:"clusters"`
+
+	// ManagementAudiences is the list of audiences for tokens issued by the
+	// management cluster. Workload tokens carrying any of these audiences are
+	// rejected to prevent exchange loops.
+	// Defaults to DefaultManagementAudiences.
+	ManagementAudiences []string `yaml:"management_audiences,omitempty"`
+
+	// IdentityMappings map workload identities to management cluster service
+	// accounts. The first matching mapping is used. This is optional; by
+	// default identities exchange for the service account with the same
+	// namespace and name.
+	IdentityMappings []IdentityMapping `yaml:"identity_mappings,omitempty"`
+
+	// RequireTrustedSubjects requires management service accounts to list the
+	// workload identities allowed to exchange for them in the
+	// "tokensmith.holos.run/trusted-subjects" annotation.
+	RequireTrustedSubjects bool `yaml:"require_trusted_subjects,omitempty"`
+
+	// ExchangeProfiles set the audiences, expiration and bound object of
+	// exchanged tokens. The first matching profile is used. This is optional;
+	// by default tokens use the management audiences and --token-expiration.
+	ExchangeProfiles []ExchangeProfile `yaml:"exchange_profiles,omitempty"`
 }
 
 // ClusterConfig defines the configuration for a single workload cluster.
@@ -18,18 +107,273 @@ type ClusterConfig struct {
 	// Name is a human-readable identifier for the cluster.
 	Name string `yaml:"name"`
 
+	// Type is the kind of issuer: ClusterTypeKubernetes for a Kubernetes
+	// cluster issuing service account tokens, ClusterTypeOIDC for any other
+	// OIDC issuer whose tokens are mapped to service accounts with Mappings,
+	// or ClusterTypeSPIFFE for a SPIFFE trust domain issuing JWT-SVIDs.
+	// Defaults to ClusterTypeKubernetes.
+	Type string `yaml:"type,omitempty"`
+
 	// Issuer is the OIDC issuer URL for the cluster (e.g., "https://kubernetes.default.svc").
 	// This must match the "iss" claim in tokens from this cluster.
+	// Optional for ClusterTypeSPIFFE, whose tokens are matched by trust domain.
 	Issuer string `yaml:"issuer"`
 
+	// TrustDomain is the SPIFFE trust domain of JWT-SVIDs from this cluster,
+	// such as "cluster.local". Required for ClusterTypeSPIFFE and not allowed
+	// otherwise.
+	TrustDomain string `yaml:"trust_domain,omitempty"`
+
+	// SPIFFEPaths are templates for the path of SPIFFE IDs, such as
+	// "/ns/{namespace}/sa/{name}". Each template must contain the {namespace}
+	// and {name} placeholders as whole path segments. The first template
+	// matching a SPIFFE ID identifies the service account.
+	// Defaults to DefaultSPIFFEPaths. Only allowed for ClusterTypeSPIFFE.
+	SPIFFEPaths []string `yaml:"spiffe_paths,omitempty"`
+
+	// Audiences is the list of audiences accepted from this cluster.
+	// Tokens must carry at least one of these values in the "aud" claim.
+	Audiences []string `yaml:"audiences"`
+
+	// ManagementNamespaces are globs of the management cluster namespaces
+	// identities from this cluster may exchange into, e.g. "tenant-a-*".
+	// Defaults to any namespace. SystemNamespaces are denied unless listed
+	// by name.
+	ManagementNamespaces []string `yaml:"management_namespaces,omitempty"`
+
+	// Validation selects how tokens from this cluster are validated:
+	// ValidationJWKS, ValidationTokenReview or ValidationJWKSThenTokenReview.
+	// Defaults to ValidationJWKS.
+	Validation string `yaml:"validation,omitempty"`
+
 	// JWKSURI is the URL to fetch the JSON Web Key Set from.
-	// This is optional if JWKSData is provided inline.
+	// This is optional if JWKSData is provided inline or Discovery is enabled.
 	JWKSURI string `yaml:"jwks_uri,omitempty"`
 
+	// JWKSFile is the path to a file containing the JSON Web Key Set, such as
+	// a mounted ConfigMap or a SPIFFE trust bundle written by a SPIRE agent.
+	// The file is watched and reloaded when it changes.
+	// This is mutually exclusive with JWKSURI, JWKSData and Discovery.
+	JWKSFile string `yaml:"jwks_file,omitempty"`
+
+	// Discovery enables OIDC discovery of the JWKS URI and supported signing
+	// algorithms from "<issuer>/.well-known/openid-configuration".
+	// This is mutually exclusive with JWKSURI and JWKSData.
+	Discovery bool `yaml:"discovery,omitempty"`
+
+	// MaxStale is how long the last good key set fetched from JWKSURI or via
+	// Discovery is served after it expires while refreshes keep failing.
+	// Defaults to DefaultMaxStale.
+	MaxStale time.Duration `yaml:"max_stale,omitempty"`
+
 	// JWKSData contains the JSON Web Key Set data inline.
 	// This is optional if JWKSURI is provided.
 	// Use this to avoid runtime network calls.
 	JWKSData *jose.JSONWebKeySet `yaml:"jwks_data,omitempty"`
+
+	// SigningAlgorithms restricts the algorithms tokens from this cluster may
+	// be signed with. This is optional; by default the algorithms advertised
+	// by Discovery are permitted, or any of SupportedSigningAlgorithms.
+	SigningAlgorithms []jose.SignatureAlgorithm `yaml:"signing_algorithms,omitempty"`
+
+	// ClaimPaths overrides where the service account identity is read from
+	// in token claims. This is optional; by default both the nested
+	// "kubernetes.io" claims and the legacy flat claims are accepted.
+	ClaimPaths *ClaimPaths `yaml:"claim_paths,omitempty"`
+
+	// Mappings map the claims of tokens from an OIDC issuer to a management
+	// cluster service account. The first rule whose conditions match is used.
+	// Required for ClusterTypeOIDC and not allowed otherwise.
+	Mappings []MappingRule `yaml:"mappings,omitempty"`
+
+	// ClaimValidation tightens validation of the standard token claims.
+	// This is optional; by default only exp, nbf and iat are checked when
+	// present, with DefaultLeeway for clock skew.
+	ClaimValidation *ClaimValidation `yaml:"claim_validation,omitempty"`
+
+	// Kubeconfig is the path to a kubeconfig for the workload cluster API server.
+	// This is optional and only required for features that query the workload
+	// cluster, such as VerifyBoundPod. When set, JWKS and discovery documents
+	// are fetched through the authenticated API server client.
+	// This is mutually exclusive with KubeconfigSecret.
+	Kubeconfig string `yaml:"kubeconfig,omitempty"`
+
+	// KubeconfigSecret references a Secret in the management cluster holding
+	// a kubeconfig for the workload cluster API server. It is used like
+	// Kubeconfig and is mutually exclusive with it.
+	KubeconfigSecret *SecretKeyRef `yaml:"kubeconfig_secret,omitempty"`
+
+	// TLS configures TLS for fetching JWKS and discovery documents.
+	// This is optional and doesn't apply to clusters with workload access,
+	// which use the TLS settings from their kubeconfig.
+	TLS *TLSConfig `yaml:"tls,omitempty"`
+
+	// ProxyURL is the HTTP proxy for fetching JWKS and discovery documents.
+	// If empty, the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment
+	// variables are used.
+	ProxyURL string `yaml:"proxy_url,omitempty"`
+
+	// MaxResponseBytes limits the size of JWKS and discovery responses.
+	// Defaults to DefaultMaxResponseBytes.
+	MaxResponseBytes int64 `yaml:"max_response_bytes,omitempty"`
+
+	// MaxRedirects is the maximum number of redirects followed when fetching
+	// JWKS and discovery documents. Redirects from HTTPS to HTTP are never
+	// followed. Defaults to 0, which rejects all redirects.
+	MaxRedirects int `yaml:"max_redirects,omitempty"`
+
+	// LegacyTokens accepts legacy Secret-based service account tokens from
+	// this cluster. This is optional; by default legacy tokens are rejected.
+	// Requires Kubeconfig or KubeconfigSecret.
+	LegacyTokens *LegacyTokens `yaml:"legacy_tokens,omitempty"`
+
+	// ReplayProtection tracks the jti of tokens from this cluster and acts when
+	// a token is presented from a different source than it was first seen
+	// from. This is optional; by default tokens may be presented from anywhere.
+	ReplayProtection *ReplayProtection `yaml:"replay_protection,omitempty"`
+
+	// VerifyBoundPod requires tokens to be bound to a pod that still exists in
+	// the workload cluster with the same name and UID.
+	// Requires Kubeconfig or KubeconfigSecret.
+	VerifyBoundPod bool `yaml:"verify_bound_pod,omitempty"`
+
+	// VerifyServiceAccount requires tokens to name a service account that
+	// still exists in the workload cluster with the same UID. Service accounts
+	// are watched with an informer, so no API call is made per token.
+	// Requires Kubeconfig or KubeconfigSecret.
+	VerifyServiceAccount bool `yaml:"verify_service_account,omitempty"`
+}
+
+// TLSConfig configures TLS for HTTPS requests to a cluster issuer.
+type TLSConfig struct {
+	// CAFile is the path to a PEM bundle of CA certificates trusted instead
+	// of the system roots.
+	CAFile string `yaml:"ca_file,omitempty"`
+
+	// ServerName overrides the server name used to verify the certificate.
+	ServerName string `yaml:"server_name,omitempty"`
+
+	// CertFile is the path to a PEM client certificate.
+	// Requires KeyFile.
+	CertFile string `yaml:"cert_file,omitempty"`
+
+	// KeyFile is the path to the PEM private key for CertFile.
+	KeyFile string `yaml:"key_file,omitempty"`
+}
+
+// DefaultKubeconfigSecretKey is the default Secret key holding a kubeconfig.
+const DefaultKubeconfigSecretKey = "kubeconfig"
+
+// SecretKeyRef references a key in a Secret in the management cluster.
+type SecretKeyRef struct {
+	// Namespace is the namespace of the Secret.
+	Namespace string `yaml:"namespace"`
+
+	// Name is the name of the Secret.
+	Name string `yaml:"name"`
+
+	// Key is the key within the Secret data.
+	// Defaults to DefaultKubeconfigSecretKey.
+	Key string `yaml:"key,omitempty"`
+}
+
+// LegacyTokens configures acceptance of legacy Secret-based service account
+// tokens, which never expire.
+type LegacyTokens struct {
+	// MaxAge rejects legacy tokens whose Secret was created longer ago than it.
+	// Required.
+	MaxAge time.Duration `yaml:"max_age"`
+}
+
+// Replay protection actions.
+const (
+	// ReplayActionFlag accepts replayed tokens and logs a warning.
+	ReplayActionFlag = "flag"
+
+	// ReplayActionDeny rejects replayed tokens.
+	ReplayActionDeny = "deny"
+)
+
+// ReplayProtection configures detection of tokens replayed from a different
+// source, identified by peer address and SPIFFE principal.
+type ReplayProtection struct {
+	// Action is taken when a replayed token is detected, either
+	// ReplayActionFlag or ReplayActionDeny. Defaults to ReplayActionFlag.
+	Action string `yaml:"action,omitempty"`
+}
+
+// GetAction returns the replay action, or ReplayActionFlag if unset.
+func (r *ReplayProtection) GetAction() string {
+	if r.Action == "" {
+		return ReplayActionFlag
+	}
+	return r.Action
+}
+
+// DefaultLeeway is the default clock skew tolerated when validating the
+// time-based claims of a token.
+const DefaultLeeway = time.Minute
+
+// ClaimValidation configures validation of the standard token claims.
+type ClaimValidation struct {
+	// Leeway is the clock skew tolerated when validating exp, nbf and iat.
+	// Defaults to DefaultLeeway.
+	Leeway time.Duration `yaml:"leeway,omitempty"`
+
+	// MaxLifetime rejects tokens whose lifetime, exp minus iat, exceeds it.
+	// Setting it requires tokens to carry both exp and iat.
+	MaxLifetime time.Duration `yaml:"max_lifetime,omitempty"`
+
+	// MaxAge rejects tokens issued longer ago than it.
+	// Setting it requires tokens to carry iat.
+	MaxAge time.Duration `yaml:"max_age,omitempty"`
+
+	// RequireIssuedAt rejects tokens without an iat claim.
+	RequireIssuedAt bool `yaml:"require_iat,omitempty"`
+
+	// RequireNotBefore rejects tokens without an nbf claim.
+	RequireNotBefore bool `yaml:"require_nbf,omitempty"`
+
+	// RequireSubjectMatch rejects tokens whose sub claim is not
+	// "system:serviceaccount:<namespace>:<name>" for the service account
+	// identified by the kubernetes claims.
+	RequireSubjectMatch bool `yaml:"require_subject_match,omitempty"`
+}
+
+// GetLeeway returns the clock skew tolerance, or DefaultLeeway if unset.
+func (v *ClaimValidation) GetLeeway() time.Duration {
+	if v == nil || v.Leeway == 0 {
+		return DefaultLeeway
+	}
+	return v.Leeway
+}
+
+// Validate checks that the claim validation settings are valid.
+func (v *ClaimValidation) Validate() error {
+	if v.Leeway < 0 {
+		return errors.New("leeway must not be negative")
+	}
+	if v.MaxLifetime < 0 {
+		return errors.New("max_lifetime must not be negative")
+	}
+	if v.MaxAge < 0 {
+		return errors.New("max_age must not be negative")
+	}
+	return nil
+}
+
+// ClaimPaths defines the location of service account identity claims.
+// Each path is a list of keys traversing nested claim objects, for example
+// ["kubernetes.io", "serviceaccount", "name"]. Unset paths use the defaults.
+type ClaimPaths struct {
+	// Namespace is the path to the service account namespace claim.
+	Namespace []string `yaml:"namespace,omitempty"`
+
+	// Name is the path to the service account name claim.
+	Name []string `yaml:"name,omitempty"`
+
+	// UID is the path to the service account UID claim.
+	UID []string `yaml:"uid,omitempty"`
 }
 
 // Validate checks that the configuration is valid.
@@ -39,32 +383,140 @@ func (c *ClustersConfig) Validate() error {
 	}
 
 	issuers := make(map[string]bool)
+	trustDomains := make(map[string]bool)
 	names := make(map[string]bool)
+	managementAudiences := c.GetManagementAudiences()
+	legacyTokenReviewCluster := ""
 
-	for i, cluster := range c.Clusters {
+	for i := range c.Clusters {
+		// Validate in place so compiled mapping rules are kept
+		cluster := &c.Clusters[i]
 		if err := cluster.Validate(); err != nil {
 			return fmt.Errorf("cluster[%d]: %w", i, err)
 		}
 
-		// Check for duplicate issuers
-		if issuers[cluster.Issuer] {
-			return fmt.Errorf("cluster[%d]: duplicate issuer %q", i, cluster.Issuer)
+		// Workload audiences must be distinct from management audiences,
+		// otherwise every token from the cluster would be rejected.
+		for _, aud := range cluster.Audiences {
+			if slices.Contains(managementAudiences, aud) {
+				return fmt.Errorf("cluster[%d]: audience %q is reserved for the management cluster", i, aud)
+			}
+		}
+
+		// Check for duplicate issuers. SPIFFE clusters may omit the issuer.
+		if cluster.Issuer != "" {
+			if issuers[cluster.Issuer] {
+				return fmt.Errorf("cluster[%d]: duplicate issuer %q", i, cluster.Issuer)
+			}
+			issuers[cluster.Issuer] = true
+		}
+
+		// Check for duplicate trust domains
+		if cluster.TrustDomain != "" {
+			if trustDomains[cluster.TrustDomain] {
+				return fmt.Errorf("cluster[%d]: duplicate trust_domain %q", i, cluster.TrustDomain)
+			}
+			trustDomains[cluster.TrustDomain] = true
 		}
-		issuers[cluster.Issuer] = true
 
 		// Check for duplicate names
 		if names[cluster.Name] {
 			return fmt.Errorf("cluster[%d]: duplicate name %q", i, cluster.Name)
 		}
 		names[cluster.Name] = true
+
+		// Legacy tokens share one issuer and TokenReview clusters have no key
+		// set to match their signing key, so only one such cluster may receive
+		// them. Otherwise one tenant's API server would see another's tokens.
+		if cluster.LegacyTokens != nil && !cluster.UsesJWKS() {
+			if legacyTokenReviewCluster != "" {
+				return fmt.Errorf("cluster[%d]: legacy_tokens is already set on tokenreview cluster %q; at most one tokenreview cluster may accept legacy tokens",
+					i, legacyTokenReviewCluster)
+			}
+			legacyTokenReviewCluster = cluster.Name
+		}
 	}
 
+	for i := range c.IdentityMappings {
+		mapping := &c.IdentityMappings[i]
+		if err := mapping.Validate(); err != nil {
+			return fmt.Errorf("identity_mappings[%d]: %w", i, err)
+		}
+		for _, name := range mapping.Clusters {
+			if !names[name] {
+				return fmt.Errorf("identity_mappings[%d]: unknown cluster %q", i, name)
+			}
+		}
+	}
+
+	profiles := make(map[string]bool)
+	for i := range c.ExchangeProfiles {
+		profile := &c.ExchangeProfiles[i]
+		if err := profile.Validate(); err != nil {
+			return fmt.Errorf("exchange_profiles[%d]: %w", i, err)
+		}
+		if profiles[profile.Name] {
+			return fmt.Errorf("exchange_profiles[%d]: duplicate name %q", i, profile.Name)
+		}
+		profiles[profile.Name] = true
+		for _, name := range profile.Clusters {
+			if !names[name] {
+				return fmt.Errorf("exchange_profiles[%d]: unknown cluster %q", i, name)
+			}
+		}
+
+		// Exchanged tokens must not be accepted by a workload cluster,
+		// otherwise they could be exchanged again
+		for _, aud := range profile.Audiences {
+			if cluster := c.FindByAudience(aud); cluster != nil {
+				return fmt.Errorf("exchange_profiles[%d]: audience %q is accepted by cluster %q", i, aud, cluster.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetManagementAudiences returns the management cluster audiences,
+// falling back to DefaultManagementAudiences if none are configured.
+func (c *ClustersConfig) GetManagementAudiences() []string {
+	if len(c.ManagementAudiences) == 0 {
+		return DefaultManagementAudiences
+	}
+	return c.ManagementAudiences
+}
+
+// GetExchangedAudiences returns every audience exchanged tokens may be issued
+// for: the management audiences and the audiences of each exchange profile.
+func (c *ClustersConfig) GetExchangedAudiences() []string {
+	audiences := slices.Clone(c.GetManagementAudiences())
+	for i := range c.ExchangeProfiles {
+		for _, aud := range c.ExchangeProfiles[i].Audiences {
+			if !slices.Contains(audiences, aud) {
+				audiences = append(audiences, aud)
+			}
+		}
+	}
+	return audiences
+}
+
+// FindByAudience returns the first cluster that accepts tokens for the given
+// audience. Returns nil if no cluster accepts the audience.
+func (c *ClustersConfig) FindByAudience(audience string) *ClusterConfig {
+	for i := range c.Clusters {
+		if slices.Contains(c.Clusters[i].Audiences, audience) {
+			return &c.Clusters[i]
+		}
+	}
 	return nil
 }
 
 // FindByIssuer returns the cluster configuration for the given issuer.
 // Returns nil if no cluster matches the issuer.
 func (c *ClustersConfig) FindByIssuer(issuer string) *ClusterConfig {
+	if issuer == "" {
+		return nil
+	}
 	for i := range c.Clusters {
 		if c.Clusters[i].Issuer == issuer {
 			return &c.Clusters[i]
@@ -73,19 +525,227 @@ func (c *ClustersConfig) FindByIssuer(issuer string) *ClusterConfig {
 	return nil
 }
 
+// FindByTrustDomain returns the SPIFFE cluster configuration for the given
+// trust domain. Returns nil if no cluster matches the trust domain.
+func (c *ClustersConfig) FindByTrustDomain(trustDomain string) *ClusterConfig {
+	if trustDomain == "" {
+		return nil
+	}
+	for i := range c.Clusters {
+		if c.Clusters[i].TrustDomain == trustDomain {
+			return &c.Clusters[i]
+		}
+	}
+	return nil
+}
+
+// GetSPIFFEPaths returns the SPIFFE ID path templates, or DefaultSPIFFEPaths
+// if none are configured.
+func (c *ClusterConfig) GetSPIFFEPaths() []string {
+	if len(c.SPIFFEPaths) == 0 {
+		return DefaultSPIFFEPaths
+	}
+	return c.SPIFFEPaths
+}
+
+// FindByName returns the cluster configuration with the given name.
+// Returns nil if no cluster has the name.
+func (c *ClustersConfig) FindByName(name string) *ClusterConfig {
+	for i := range c.Clusters {
+		if c.Clusters[i].Name == name {
+			return &c.Clusters[i]
+		}
+	}
+	return nil
+}
+
+// LegacyTokenReviewCluster returns the cluster with validation tokenreview
+// that accepts legacy tokens, or nil if there is none. Validate allows at most
+// one.
+func (c *ClustersConfig) LegacyTokenReviewCluster() *ClusterConfig {
+	for i := range c.Clusters {
+		if c.Clusters[i].LegacyTokens != nil && !c.Clusters[i].UsesJWKS() {
+			return &c.Clusters[i]
+		}
+	}
+	return nil
+}
+
+// AllowsManagementNamespace reports whether identities from the cluster may
+// exchange into a management cluster namespace.
+func (c *ClusterConfig) AllowsManagementNamespace(namespace string) bool {
+	if slices.Contains(SystemNamespaces, namespace) {
+		return slices.Contains(c.ManagementNamespaces, namespace)
+	}
+	if len(c.ManagementNamespaces) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(c.ManagementNamespaces, func(pattern string) bool {
+		matched, _ := path.Match(pattern, namespace)
+		return matched
+	})
+}
+
+// GetType returns the issuer type, or ClusterTypeKubernetes if unset.
+func (c *ClusterConfig) GetType() string {
+	if c.Type == "" {
+		return ClusterTypeKubernetes
+	}
+	return c.Type
+}
+
+// GetValidation returns the validation mode, or ValidationJWKS if unset.
+func (c *ClusterConfig) GetValidation() string {
+	if c.Validation == "" {
+		return ValidationJWKS
+	}
+	return c.Validation
+}
+
+// UsesJWKS reports whether tokens from the cluster are verified with its JWKS.
+func (c *ClusterConfig) UsesJWKS() bool {
+	return c.GetValidation() != ValidationTokenReview
+}
+
+// UsesTokenReview reports whether tokens from the cluster are reviewed by its
+// API server with the TokenReview API.
+func (c *ClusterConfig) UsesTokenReview() bool {
+	return c.GetValidation() != ValidationJWKS
+}
+
+// IsRemote reports whether the cluster's keys are fetched over the network.
+func (c *ClusterConfig) IsRemote() bool {
+	if c.JWKSData != nil || c.JWKSFile != "" {
+		return false
+	}
+	return c.JWKSURI != "" || c.Discovery
+}
+
+// HasWorkloadAccess reports whether credentials for the workload cluster API
+// server are configured.
+func (c *ClusterConfig) HasWorkloadAccess() bool {
+	return c.Kubeconfig != "" || c.KubeconfigSecret != nil
+}
+
+// GetMaxResponseBytes returns the response size limit, falling back to
+// DefaultMaxResponseBytes.
+func (c *ClusterConfig) GetMaxResponseBytes() int64 {
+	if c.MaxResponseBytes == 0 {
+		return DefaultMaxResponseBytes
+	}
+	return c.MaxResponseBytes
+}
+
+// GetMaxStale returns the max-stale window, falling back to DefaultMaxStale.
+func (c *ClusterConfig) GetMaxStale() time.Duration {
+	if c.MaxStale == 0 {
+		return DefaultMaxStale
+	}
+	return c.MaxStale
+}
+
 // Validate checks that the cluster configuration is valid.
 func (c *ClusterConfig) Validate() error {
 	if c.Name == "" {
 		return errors.New("name is required")
 	}
 
-	if c.Issuer == "" {
+	if c.Issuer == "" && c.GetType() != ClusterTypeSPIFFE {
 		return errors.New("issuer is required")
 	}
 
-	// At least one of JWKSURI or JWKSData must be provided
-	if c.JWKSURI == "" && c.JWKSData == nil {
-		return errors.New("either jwks_uri or jwks_data must be provided")
+	if len(c.Audiences) == 0 {
+		return errors.New("at least one audience is required")
+	}
+
+	for _, aud := range c.Audiences {
+		if aud == "" {
+			return errors.New("audiences must not contain empty values")
+		}
+	}
+
+	if c.GetType() != ClusterTypeSPIFFE && (c.TrustDomain != "" || len(c.SPIFFEPaths) > 0) {
+		return errors.New("trust_domain and spiffe_paths require type spiffe")
+	}
+
+	for _, pattern := range c.ManagementNamespaces {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("management_namespaces: invalid glob %q: %w", pattern, err)
+		}
+	}
+
+	switch c.GetType() {
+	case ClusterTypeKubernetes:
+		if len(c.Mappings) > 0 {
+			return errors.New("mappings require type oidc")
+		}
+	case ClusterTypeOIDC:
+		if len(c.Mappings) == 0 {
+			return errors.New("type oidc requires at least one mapping")
+		}
+		if c.GetValidation() != ValidationJWKS {
+			return errors.New("type oidc requires jwks validation")
+		}
+		if c.ClaimPaths != nil || c.LegacyTokens != nil || c.VerifyBoundPod || c.VerifyServiceAccount {
+			return errors.New("type oidc cannot be combined with claim_paths, legacy_tokens, verify_bound_pod or verify_service_account")
+		}
+		for i := range c.Mappings {
+			if err := c.Mappings[i].Validate(); err != nil {
+				return fmt.Errorf("mappings[%d]: %w", i, err)
+			}
+		}
+	case ClusterTypeSPIFFE:
+		if err := ValidateTrustDomain(c.TrustDomain); err != nil {
+			return fmt.Errorf("trust_domain: %w", err)
+		}
+		if c.GetValidation() != ValidationJWKS {
+			return errors.New("type spiffe requires jwks validation")
+		}
+		if c.Discovery {
+			return errors.New("type spiffe requires a trust bundle from jwks_uri, jwks_file or jwks_data")
+		}
+		if len(c.Mappings) > 0 || c.ClaimPaths != nil || c.LegacyTokens != nil || c.VerifyBoundPod || c.VerifyServiceAccount {
+			return errors.New("type spiffe cannot be combined with mappings, claim_paths, legacy_tokens, verify_bound_pod or verify_service_account")
+		}
+		for i, path := range c.SPIFFEPaths {
+			if err := ValidateSPIFFEPath(path); err != nil {
+				return fmt.Errorf("spiffe_paths[%d]: %w", i, err)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown type %q", c.Type)
+	}
+
+	switch c.GetValidation() {
+	case ValidationJWKS:
+	case ValidationTokenReview:
+		if c.JWKSURI != "" || c.JWKSData != nil || c.JWKSFile != "" || c.Discovery ||
+			len(c.SigningAlgorithms) > 0 || c.ClaimPaths != nil || c.ClaimValidation != nil ||
+			c.ReplayProtection != nil || c.VerifyBoundPod || c.VerifyServiceAccount {
+			return errors.New("tokenreview validation cannot be combined with key sources or JWKS validation settings")
+		}
+	case ValidationJWKSThenTokenReview:
+	default:
+		return fmt.Errorf("unknown validation %q", c.Validation)
+	}
+
+	if c.UsesTokenReview() && !c.HasWorkloadAccess() {
+		return fmt.Errorf("%s validation requires kubeconfig or kubeconfig_secret", c.GetValidation())
+	}
+
+	// Discovery replaces the explicit key source
+	if c.Discovery && (c.JWKSURI != "" || c.JWKSData != nil) {
+		return errors.New("discovery cannot be combined with jwks_uri or jwks_data")
+	}
+
+	// A JWKS file replaces all other key sources
+	if c.JWKSFile != "" && (c.JWKSURI != "" || c.JWKSData != nil || c.Discovery) {
+		return errors.New("jwks_file cannot be combined with jwks_uri, jwks_data or discovery")
+	}
+
+	// At least one of JWKSURI, JWKSData, JWKSFile or Discovery must be provided
+	if c.UsesJWKS() && c.JWKSURI == "" && c.JWKSData == nil && c.JWKSFile == "" && !c.Discovery {
+		return errors.New("one of jwks_uri, jwks_data, jwks_file or discovery must be provided")
 	}
 
 	// If JWKSData is provided, it must contain at least one key
@@ -93,5 +753,104 @@ func (c *ClusterConfig) Validate() error {
 		return errors.New("jwks_data must contain at least one key")
 	}
 
+	if c.MaxStale < 0 {
+		return errors.New("max_stale must not be negative")
+	}
+
+	for _, alg := range c.SigningAlgorithms {
+		if !slices.Contains(SupportedSigningAlgorithms, alg) {
+			return fmt.Errorf("unsupported signing algorithm %q", alg)
+		}
+	}
+
+	if c.Kubeconfig != "" && c.KubeconfigSecret != nil {
+		return errors.New("kubeconfig and kubeconfig_secret are mutually exclusive")
+	}
+
+	if c.KubeconfigSecret != nil {
+		if c.KubeconfigSecret.Namespace == "" || c.KubeconfigSecret.Name == "" {
+			return errors.New("kubeconfig_secret requires namespace and name")
+		}
+	}
+
+	if c.VerifyBoundPod && !c.HasWorkloadAccess() {
+		return errors.New("verify_bound_pod requires kubeconfig or kubeconfig_secret")
+	}
+
+	if c.VerifyServiceAccount && !c.HasWorkloadAccess() {
+		return errors.New("verify_service_account requires kubeconfig or kubeconfig_secret")
+	}
+
+	if c.HasWorkloadAccess() && (c.TLS != nil || c.ProxyURL != "") {
+		return errors.New("tls and proxy_url cannot be combined with kubeconfig or kubeconfig_secret")
+	}
+
+	if c.TLS != nil && (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		return errors.New("tls: cert_file and key_file must be set together")
+	}
+
+	if c.ProxyURL != "" {
+		u, err := url.Parse(c.ProxyURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid proxy_url %q", c.ProxyURL)
+		}
+	}
+
+	if c.MaxResponseBytes < 0 {
+		return errors.New("max_response_bytes must not be negative")
+	}
+
+	if c.MaxRedirects < 0 {
+		return errors.New("max_redirects must not be negative")
+	}
+
+	// Relative JWKS URIs are resolved against the workload API server
+	if strings.HasPrefix(c.JWKSURI, "/") && !c.HasWorkloadAccess() {
+		return errors.New("relative jwks_uri requires kubeconfig or kubeconfig_secret")
+	}
+
+	if c.ClaimPaths != nil {
+		if err := c.ClaimPaths.Validate(); err != nil {
+			return fmt.Errorf("claim_paths: %w", err)
+		}
+	}
+
+	if c.LegacyTokens != nil {
+		if c.LegacyTokens.MaxAge <= 0 {
+			return errors.New("legacy_tokens: max_age must be positive")
+		}
+		if !c.HasWorkloadAccess() {
+			return errors.New("legacy_tokens requires kubeconfig or kubeconfig_secret")
+		}
+	}
+
+	if c.ReplayProtection != nil {
+		switch c.ReplayProtection.GetAction() {
+		case ReplayActionFlag, ReplayActionDeny:
+		default:
+			return fmt.Errorf("replay_protection: unknown action %q", c.ReplayProtection.Action)
+		}
+	}
+
+	if c.ClaimValidation != nil {
+		if err := c.ClaimValidation.Validate(); err != nil {
+			return fmt.Errorf("claim_validation: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Validate checks that the claim paths are valid.
+func (p *ClaimPaths) Validate() error {
+	if slices.Contains(p.Namespace, "") {
+		return errors.New("namespace: path must not contain empty keys")
+	}
+	if slices.Contains(p.Name, "") {
+		return errors.New("name: path must not contain empty keys")
+	}
+	if slices.Contains(p.UID, "") {
+		return errors.New("uid: path must not contain empty keys")
+	}
 	return nil
 }
@@ -0,0 +1,261 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+// newTestClustersConfig returns a valid configuration with one cluster
+// verifying tokens with a remote JWKS.
+func newTestClustersConfig() *ClustersConfig {
+	return &ClustersConfig{
+		Clusters: []ClusterConfig{
+			{
+				Name:      "cluster",
+				Issuer:    "https://cluster.example.com",
+				Audiences: []string{"tokensmith"},
+				JWKSURI:   "https://cluster.example.com/openid/v1/jwks",
+			},
+		},
+	}
+}
+
+func TestClustersConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *ClustersConfig)
+		// expectError is a substring of the expected error, or empty if the
+		// configuration is valid.
+		expectError string
+	}{
+		{
+			name:   "valid",
+			modify: func(c *ClustersConfig) {},
+		},
+		{
+			name:        "missing audiences",
+			modify:      func(c *ClustersConfig) { c.Clusters[0].Audiences = nil },
+			expectError: "at least one audience is required",
+		},
+		{
+			name:        "empty audience",
+			modify:      func(c *ClustersConfig) { c.Clusters[0].Audiences = []string{"tokensmith", ""} },
+			expectError: "audiences must not contain empty values",
+		},
+		{
+			name: "audience is the default management audience",
+			modify: func(c *ClustersConfig) {
+				c.Clusters[0].Audiences = []string{"tokensmith", DefaultManagementAudiences[0]}
+			},
+			expectError: "reserved for the management cluster",
+		},
+		{
+			name: "audience is a configured management audience",
+			modify: func(c *ClustersConfig) {
+				c.ManagementAudiences = []string{"management"}
+				c.Clusters[0].Audiences = []string{"management"}
+			},
+			expectError: "reserved for the management cluster",
+		},
+		{
+			name: "configured management audiences replace the default",
+			modify: func(c *ClustersConfig) {
+				c.ManagementAudiences = []string{"management"}
+				c.Clusters[0].Audiences = []string{DefaultManagementAudiences[0]}
+			},
+		},
+		{
+			name:   "tokenreview with kubeconfig",
+			modify: func(c *ClustersConfig) { useTokenReview(&c.Clusters[0]) },
+		},
+		{
+			name: "tokenreview with kubeconfig secret",
+			modify: func(c *ClustersConfig) {
+				useTokenReview(&c.Clusters[0])
+				c.Clusters[0].Kubeconfig = ""
+				c.Clusters[0].KubeconfigSecret = &SecretKeyRef{Namespace: "tokensmith", Name: "cluster-kubeconfig"}
+			},
+		},
+		{
+			name: "tokenreview without kubeconfig",
+			modify: func(c *ClustersConfig) {
+				useTokenReview(&c.Clusters[0])
+				c.Clusters[0].Kubeconfig = ""
+			},
+			expectError: "tokenreview validation requires kubeconfig or kubeconfig_secret",
+		},
+		{
+			name: "tokenreview with jwks_uri",
+			modify: func(c *ClustersConfig) {
+				useTokenReview(&c.Clusters[0])
+				c.Clusters[0].JWKSURI = "https://cluster.example.com/openid/v1/jwks"
+			},
+			expectError: "tokenreview validation cannot be combined",
+		},
+		{
+			name: "tokenreview with discovery",
+			modify: func(c *ClustersConfig) {
+				useTokenReview(&c.Clusters[0])
+				c.Clusters[0].Discovery = true
+			},
+			expectError: "tokenreview validation cannot be combined",
+		},
+		{
+			name: "tokenreview with signing algorithms",
+			modify: func(c *ClustersConfig) {
+				useTokenReview(&c.Clusters[0])
+				c.Clusters[0].SigningAlgorithms = []jose.SignatureAlgorithm{jose.RS256}
+			},
+			expectError: "tokenreview validation cannot be combined",
+		},
+		{
+			name: "tokenreview with claim validation",
+			modify: func(c *ClustersConfig) {
+				useTokenReview(&c.Clusters[0])
+				c.Clusters[0].ClaimValidation = &ClaimValidation{RequireIssuedAt: true}
+			},
+			expectError: "tokenreview validation cannot be combined",
+		},
+		{
+			name: "tokenreview with replay protection",
+			modify: func(c *ClustersConfig) {
+				useTokenReview(&c.Clusters[0])
+				c.Clusters[0].ReplayProtection = &ReplayProtection{}
+			},
+			expectError: "tokenreview validation cannot be combined",
+		},
+		{
+			name: "tokenreview with verify_bound_pod",
+			modify: func(c *ClustersConfig) {
+				useTokenReview(&c.Clusters[0])
+				c.Clusters[0].VerifyBoundPod = true
+			},
+			expectError: "tokenreview validation cannot be combined",
+		},
+		{
+			name: "tokenreview with verify_service_account",
+			modify: func(c *ClustersConfig) {
+				useTokenReview(&c.Clusters[0])
+				c.Clusters[0].VerifyServiceAccount = true
+			},
+			expectError: "tokenreview validation cannot be combined",
+		},
+		{
+			name: "jwks-then-tokenreview with kubeconfig",
+			modify: func(c *ClustersConfig) {
+				c.Clusters[0].Validation = ValidationJWKSThenTokenReview
+				c.Clusters[0].Kubeconfig = "/etc/tokensmith/cluster.kubeconfig"
+			},
+		},
+		{
+			name:        "jwks-then-tokenreview without kubeconfig",
+			modify:      func(c *ClustersConfig) { c.Clusters[0].Validation = ValidationJWKSThenTokenReview },
+			expectError: "jwks-then-tokenreview validation requires kubeconfig or kubeconfig_secret",
+		},
+		{
+			name:        "unknown validation",
+			modify:      func(c *ClustersConfig) { c.Clusters[0].Validation = "webhook" },
+			expectError: `unknown validation "webhook"`,
+		},
+		{
+			name: "oidc cluster with tokenreview",
+			modify: func(c *ClustersConfig) {
+				useTokenReview(&c.Clusters[0])
+				c.Clusters[0].Type = ClusterTypeOIDC
+				c.Clusters[0].Mappings = []MappingRule{{Namespace: "ci", Name: "deployer"}}
+			},
+			expectError: "type oidc requires jwks validation",
+		},
+		{
+			name: "legacy tokens on two tokenreview clusters",
+			modify: func(c *ClustersConfig) {
+				c.Clusters = append(c.Clusters, c.Clusters[0])
+				c.Clusters[1].Name = "other"
+				c.Clusters[1].Issuer = "https://other.example.com"
+				for i := range c.Clusters {
+					useTokenReview(&c.Clusters[i])
+					c.Clusters[i].LegacyTokens = &LegacyTokens{MaxAge: time.Hour}
+				}
+			},
+			expectError: "at most one tokenreview cluster may accept legacy tokens",
+		},
+		{
+			name: "claim validation",
+			modify: func(c *ClustersConfig) {
+				c.Clusters[0].ClaimValidation = &ClaimValidation{Leeway: time.Second, MaxLifetime: time.Hour, MaxAge: time.Hour}
+			},
+		},
+		{
+			name:        "negative leeway",
+			modify:      func(c *ClustersConfig) { c.Clusters[0].ClaimValidation = &ClaimValidation{Leeway: -time.Second} },
+			expectError: "claim_validation: leeway must not be negative",
+		},
+		{
+			name:        "negative max_lifetime",
+			modify:      func(c *ClustersConfig) { c.Clusters[0].ClaimValidation = &ClaimValidation{MaxLifetime: -time.Hour} },
+			expectError: "claim_validation: max_lifetime must not be negative",
+		},
+		{
+			name:        "negative max_age",
+			modify:      func(c *ClustersConfig) { c.Clusters[0].ClaimValidation = &ClaimValidation{MaxAge: -time.Hour} },
+			expectError: "claim_validation: max_age must not be negative",
+		},
+		{
+			name: "claim paths",
+			modify: func(c *ClustersConfig) {
+				c.Clusters[0].ClaimPaths = &ClaimPaths{
+					Namespace: []string{"k8s", "namespace"},
+					Name:      []string{"k8s", "serviceaccount", "name"},
+					UID:       []string{"k8s", "serviceaccount", "uid"},
+				}
+			},
+		},
+		{
+			name:        "empty claim path key in namespace",
+			modify:      func(c *ClustersConfig) { c.Clusters[0].ClaimPaths = &ClaimPaths{Namespace: []string{"k8s", ""}} },
+			expectError: "claim_paths: namespace: path must not contain empty keys",
+		},
+		{
+			name:        "empty claim path key in name",
+			modify:      func(c *ClustersConfig) { c.Clusters[0].ClaimPaths = &ClaimPaths{Name: []string{""}} },
+			expectError: "claim_paths: name: path must not contain empty keys",
+		},
+		{
+			name:        "empty claim path key in uid",
+			modify:      func(c *ClustersConfig) { c.Clusters[0].ClaimPaths = &ClaimPaths{UID: []string{"", "uid"}} },
+			expectError: "claim_paths: uid: path must not contain empty keys",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestClustersConfig()
+			tt.modify(cfg)
+
+			err := cfg.Validate()
+			if tt.expectError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Expected error containing %q, got none", tt.expectError)
+			}
+			if !strings.Contains(err.Error(), tt.expectError) {
+				t.Errorf("Expected error containing %q, got %q", tt.expectError, err)
+			}
+		})
+	}
+}
+
+// useTokenReview switches a cluster to TokenReview validation through a
+// kubeconfig.
+func useTokenReview(c *ClusterConfig) {
+	c.Validation = ValidationTokenReview
+	c.JWKSURI = ""
+	c.Kubeconfig = "/etc/tokensmith/cluster.kubeconfig"
+}
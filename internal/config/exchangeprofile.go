@@ -0,0 +1,134 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"time"
+)
+
+// MinTokenExpiration is the shortest token lifetime the TokenRequest API
+// accepts.
+const MinTokenExpiration = 10 * time.Minute
+
+// BoundObjectKinds are the kinds of objects a management cluster token may be
+// bound to.
+var BoundObjectKinds = []string{"Pod", "Secret", "Node"}
+
+// ExchangeProfile configures the tokens issued for exchanges it matches.
+// Profiles are evaluated in order and the first one whose selectors all match
+// is used. Exchanges matching no profile use the default audiences and
+// expiration.
+type ExchangeProfile struct {
+	// Name identifies the profile in logs.
+	Name string `yaml:"name"`
+
+	// Clusters restricts the profile to identities from the named clusters.
+	// Empty matches identities from any cluster.
+	Clusters []string `yaml:"clusters,omitempty"`
+
+	// Namespace is a glob the management namespace, after identity mappings,
+	// must match. Empty matches any namespace.
+	Namespace string `yaml:"namespace,omitempty"`
+
+	// ServiceAccount is a glob the management service account name, after
+	// identity mappings, must match. Empty matches any service account.
+	ServiceAccount string `yaml:"service_account,omitempty"`
+
+	// Audiences of the issued token. Defaults to the management audiences.
+	Audiences []string `yaml:"audiences,omitempty"`
+
+	// Expiration is the lifetime of the issued token. Must be at least
+	// MinTokenExpiration. Defaults to the --token-expiration flag.
+	Expiration time.Duration `yaml:"expiration,omitempty"`
+
+	// BoundObjectRef binds the issued token to an object in the namespace of
+	// the management service account. The token is invalidated when the
+	// object is deleted.
+	BoundObjectRef *BoundObjectRef `yaml:"bound_object_ref,omitempty"`
+}
+
+// BoundObjectRef references the object a management cluster token is bound to.
+type BoundObjectRef struct {
+	// Kind of the object, one of BoundObjectKinds.
+	Kind string `yaml:"kind"`
+
+	// APIVersion of the object. Defaults to "v1".
+	APIVersion string `yaml:"api_version,omitempty"`
+
+	// Name of the object.
+	Name string `yaml:"name"`
+
+	// UID of the object. If set, the object must have this UID.
+	UID string `yaml:"uid,omitempty"`
+}
+
+// GetAPIVersion returns the API version of the bound object, or "v1" if unset.
+func (r *BoundObjectRef) GetAPIVersion() string {
+	if r.APIVersion == "" {
+		return "v1"
+	}
+	return r.APIVersion
+}
+
+// Validate checks that the exchange profile is valid.
+func (p *ExchangeProfile) Validate() error {
+	if p.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if p.Namespace != "" {
+		if _, err := path.Match(p.Namespace, ""); err != nil {
+			return fmt.Errorf("namespace: invalid glob %q: %w", p.Namespace, err)
+		}
+	}
+	if p.ServiceAccount != "" {
+		if _, err := path.Match(p.ServiceAccount, ""); err != nil {
+			return fmt.Errorf("service_account: invalid glob %q: %w", p.ServiceAccount, err)
+		}
+	}
+
+	for i, aud := range p.Audiences {
+		if aud == "" {
+			return fmt.Errorf("audiences[%d]: audience must not be empty", i)
+		}
+	}
+
+	if p.Expiration != 0 && p.Expiration < MinTokenExpiration {
+		return fmt.Errorf("expiration must be at least %s", MinTokenExpiration)
+	}
+
+	if ref := p.BoundObjectRef; ref != nil {
+		if !slices.Contains(BoundObjectKinds, ref.Kind) {
+			return fmt.Errorf("bound_object_ref: kind must be one of %v, got %q", BoundObjectKinds, ref.Kind)
+		}
+		if ref.GetAPIVersion() != "v1" {
+			return fmt.Errorf("bound_object_ref: unsupported api_version %q", ref.APIVersion)
+		}
+		if ref.Name == "" {
+			return errors.New("bound_object_ref: name is required")
+		}
+	}
+
+	return nil
+}
+
+// Matches reports whether all selectors of the profile match an exchange from
+// a workload cluster into a management service account.
+func (p *ExchangeProfile) Matches(cluster, namespace, serviceAccount string) bool {
+	if len(p.Clusters) > 0 && !slices.Contains(p.Clusters, cluster) {
+		return false
+	}
+	if p.Namespace != "" {
+		if matched, err := path.Match(p.Namespace, namespace); err != nil || !matched {
+			return false
+		}
+	}
+	if p.ServiceAccount != "" {
+		if matched, err := path.Match(p.ServiceAccount, serviceAccount); err != nil || !matched {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// IdentityMapping maps validated workload identities to a management cluster
+// service account. Mappings are evaluated in order and the first one whose
+// selectors all match is used. Identities matching no mapping exchange for the
+// service account with the same namespace and name.
+type IdentityMapping struct {
+	// Clusters restricts the mapping to identities from the named clusters.
+	// Empty matches identities from any cluster.
+	Clusters []string `yaml:"clusters,omitempty"`
+
+	// Namespace is a glob the workload namespace must match, e.g. "team-*".
+	// Empty matches any namespace.
+	Namespace string `yaml:"namespace,omitempty"`
+
+	// Name is a regular expression the workload service account name must
+	// fully match. Empty matches any name.
+	Name string `yaml:"name,omitempty"`
+
+	// Deny rejects the exchange for matching identities.
+	Deny bool `yaml:"deny,omitempty"`
+
+	// TargetNamespace is a template rendered with IdentityTemplateData to
+	// produce the management namespace, e.g. "tenant-{{ .Cluster }}".
+	// Defaults to the workload namespace.
+	TargetNamespace string `yaml:"target_namespace,omitempty"`
+
+	// TargetName is a template rendered with IdentityTemplateData to produce
+	// the management service account name, e.g. "{{ .Cluster }}-{{ .Namespace }}".
+	// Defaults to the workload service account name.
+	TargetName string `yaml:"target_name,omitempty"`
+
+	// nameRegexp, targetNamespaceTemplate and targetNameTemplate are compiled
+	// by Validate.
+	nameRegexp              *regexp.Regexp
+	targetNamespaceTemplate *template.Template
+	targetNameTemplate      *template.Template
+}
+
+// IdentityTemplateData is the workload identity that identity mapping
+// templates are rendered with.
+type IdentityTemplateData struct {
+	// Cluster is the name of the workload cluster.
+	Cluster string
+
+	// Namespace is the namespace of the workload service account.
+	Namespace string
+
+	// Name is the name of the workload service account.
+	Name string
+}
+
+// Validate checks that the identity mapping is valid.
+func (m *IdentityMapping) Validate() error {
+	if m.Namespace != "" {
+		if _, err := path.Match(m.Namespace, ""); err != nil {
+			return fmt.Errorf("namespace: invalid glob %q: %w", m.Namespace, err)
+		}
+	}
+	var nameRegexp *regexp.Regexp
+	if m.Name != "" {
+		var err error
+		if nameRegexp, err = compileAnchored(m.Name); err != nil {
+			return fmt.Errorf("name: %w", err)
+		}
+	}
+
+	if m.Deny {
+		if m.TargetNamespace != "" || m.TargetName != "" {
+			return errors.New("deny cannot be combined with target_namespace or target_name")
+		}
+		m.nameRegexp = nameRegexp
+		return nil
+	}
+
+	var targetNamespaceTemplate, targetNameTemplate *template.Template
+	if m.TargetNamespace != "" {
+		var err error
+		if targetNamespaceTemplate, err = newIdentityTemplate(m.TargetNamespace); err != nil {
+			return fmt.Errorf("target_namespace: %w", err)
+		}
+	}
+	if m.TargetName != "" {
+		var err error
+		if targetNameTemplate, err = newIdentityTemplate(m.TargetName); err != nil {
+			return fmt.Errorf("target_name: %w", err)
+		}
+	}
+
+	m.nameRegexp = nameRegexp
+	m.targetNamespaceTemplate = targetNamespaceTemplate
+	m.targetNameTemplate = targetNameTemplate
+	return nil
+}
+
+// NameRegexp returns Name compiled by Validate, anchored to match the whole
+// service account name. It compiles Name if the mapping hasn't been
+// validated.
+func (m *IdentityMapping) NameRegexp() (*regexp.Regexp, error) {
+	if m.nameRegexp != nil {
+		return m.nameRegexp, nil
+	}
+	return compileAnchored(m.Name)
+}
+
+// TargetNamespaceTemplate returns the target namespace template compiled by
+// Validate, or parses it if the mapping hasn't been validated.
+func (m *IdentityMapping) TargetNamespaceTemplate() (*template.Template, error) {
+	if m.targetNamespaceTemplate != nil {
+		return m.targetNamespaceTemplate, nil
+	}
+	return NewMappingTemplate(m.TargetNamespace)
+}
+
+// TargetNameTemplate returns the target name template compiled by Validate,
+// or parses it if the mapping hasn't been validated.
+func (m *IdentityMapping) TargetNameTemplate() (*template.Template, error) {
+	if m.targetNameTemplate != nil {
+		return m.targetNameTemplate, nil
+	}
+	return NewMappingTemplate(m.TargetName)
+}
+
+// newIdentityTemplate parses a mapping template and checks that it only
+// references fields of IdentityTemplateData.
+func newIdentityTemplate(text string) (*template.Template, error) {
+	tmpl, err := NewMappingTemplate(text)
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, IdentityTemplateData{Cluster: "cluster", Namespace: "namespace", Name: "name"}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
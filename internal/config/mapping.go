@@ -0,0 +1,159 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// MappingRule maps the claims of an OIDC token to a management cluster
+// service account when all of its conditions hold.
+type MappingRule struct {
+	// Conditions must all hold for the rule to apply.
+	// A rule without conditions matches every token.
+	Conditions []ClaimCondition `yaml:"conditions,omitempty"`
+
+	// Namespace is a template rendered with the token claims to produce the
+	// namespace of the service account, e.g. "ci".
+	Namespace string `yaml:"namespace"`
+
+	// Name is a template rendered with the token claims to produce the name
+	// of the service account, e.g. "{{ .repository_owner }}-deployer".
+	Name string `yaml:"name"`
+
+	// namespaceTemplate and nameTemplate are compiled by Validate.
+	namespaceTemplate *template.Template
+	nameTemplate      *template.Template
+}
+
+// ClaimCondition is a requirement on a top-level token claim. Exactly one of
+// Equals, Matches or Contains must be set. Conditions on missing claims
+// never hold.
+type ClaimCondition struct {
+	// Claim is the name of the claim, e.g. "ref".
+	Claim string `yaml:"claim"`
+
+	// Equals requires the claim to equal the value.
+	Equals string `yaml:"equals,omitempty"`
+
+	// Matches requires the claim to fully match the regular expression.
+	Matches string `yaml:"matches,omitempty"`
+
+	// Contains requires the claim to be a list containing the value,
+	// e.g. a "groups" claim.
+	Contains string `yaml:"contains,omitempty"`
+
+	// matchesRegexp is compiled by Validate.
+	matchesRegexp *regexp.Regexp
+}
+
+// Validate checks that the mapping rule is valid.
+func (r *MappingRule) Validate() error {
+	if r.Namespace == "" || r.Name == "" {
+		return errors.New("namespace and name are required")
+	}
+	namespaceTemplate, err := NewMappingTemplate(r.Namespace)
+	if err != nil {
+		return fmt.Errorf("namespace: %w", err)
+	}
+	nameTemplate, err := NewMappingTemplate(r.Name)
+	if err != nil {
+		return fmt.Errorf("name: %w", err)
+	}
+
+	for i := range r.Conditions {
+		if err := r.Conditions[i].Validate(); err != nil {
+			return fmt.Errorf("conditions[%d]: %w", i, err)
+		}
+	}
+
+	r.namespaceTemplate = namespaceTemplate
+	r.nameTemplate = nameTemplate
+	return nil
+}
+
+// NamespaceTemplate returns the namespace template compiled by Validate, or
+// parses it if the rule hasn't been validated.
+func (r *MappingRule) NamespaceTemplate() (*template.Template, error) {
+	if r.namespaceTemplate != nil {
+		return r.namespaceTemplate, nil
+	}
+	return NewMappingTemplate(r.Namespace)
+}
+
+// NameTemplate returns the name template compiled by Validate, or parses it
+// if the rule hasn't been validated.
+func (r *MappingRule) NameTemplate() (*template.Template, error) {
+	if r.nameTemplate != nil {
+		return r.nameTemplate, nil
+	}
+	return NewMappingTemplate(r.Name)
+}
+
+// Validate checks that the claim condition is valid.
+func (c *ClaimCondition) Validate() error {
+	if c.Claim == "" {
+		return errors.New("claim is required")
+	}
+
+	operators := 0
+	for _, set := range []bool{c.Equals != "", c.Matches != "", c.Contains != ""} {
+		if set {
+			operators++
+		}
+	}
+	if operators != 1 {
+		return errors.New("exactly one of equals, matches or contains is required")
+	}
+
+	if c.Matches != "" {
+		re, err := compileAnchored(c.Matches)
+		if err != nil {
+			return fmt.Errorf("matches: %w", err)
+		}
+		c.matchesRegexp = re
+	}
+	return nil
+}
+
+// Regexp returns Matches compiled by Validate, anchored to match the whole
+// claim value. It compiles Matches if the condition hasn't been validated.
+func (c *ClaimCondition) Regexp() (*regexp.Regexp, error) {
+	if c.matchesRegexp != nil {
+		return c.matchesRegexp, nil
+	}
+	return compileAnchored(c.Matches)
+}
+
+// compileAnchored compiles a regular expression anchored to match the whole
+// value.
+func compileAnchored(expr string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + expr + ")$")
+}
+
+// mappingTemplateFuncs are the functions available in mapping templates.
+// The string being transformed is the last argument so functions can be
+// used in pipelines, e.g. {{ .repository | replace "/" "-" }}.
+var mappingTemplateFuncs = template.FuncMap{
+	"lower": strings.ToLower,
+	"replace": func(old, new, s string) string {
+		return strings.ReplaceAll(s, old, new)
+	},
+	"trimPrefix": func(prefix, s string) string {
+		return strings.TrimPrefix(s, prefix)
+	},
+	"trimSuffix": func(suffix, s string) string {
+		return strings.TrimSuffix(s, suffix)
+	},
+}
+
+// NewMappingTemplate parses a mapping template. Templates fail to render if
+// they reference a missing claim.
+func NewMappingTemplate(text string) (*template.Template, error) {
+	return template.New("mapping").
+		Funcs(mappingTemplateFuncs).
+		Option("missingkey=error").
+		Parse(text)
+}
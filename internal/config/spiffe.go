@@ -0,0 +1,66 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SPIFFE path template placeholders.
+const (
+	// SPIFFENamespacePlaceholder captures the service account namespace.
+	SPIFFENamespacePlaceholder = "{namespace}"
+
+	// SPIFFENamePlaceholder captures the service account name.
+	SPIFFENamePlaceholder = "{name}"
+)
+
+// ValidateTrustDomain checks that a SPIFFE trust domain name is valid. Trust
+// domains may only contain lowercase letters, digits, dots, dashes and
+// underscores.
+func ValidateTrustDomain(td string) error {
+	if td == "" {
+		return errors.New("trust domain is required")
+	}
+	for _, r := range td {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+		default:
+			return fmt.Errorf("invalid character %q in trust domain %q", r, td)
+		}
+	}
+	return nil
+}
+
+// ValidateSPIFFEPath checks that a SPIFFE ID path template is valid. The path
+// must start with a slash, must not contain empty segments and must contain
+// the {namespace} and {name} placeholders exactly once, each as a whole
+// segment.
+func ValidateSPIFFEPath(path string) error {
+	rest, ok := strings.CutPrefix(path, "/")
+	if !ok {
+		return fmt.Errorf("path %q must start with /", path)
+	}
+
+	var namespaces, names int
+	for _, segment := range strings.Split(rest, "/") {
+		switch segment {
+		case "":
+			return fmt.Errorf("path %q must not contain empty segments", path)
+		case SPIFFENamespacePlaceholder:
+			namespaces++
+		case SPIFFENamePlaceholder:
+			names++
+		default:
+			if strings.ContainsAny(segment, "{}") {
+				return fmt.Errorf("path %q: placeholders must be a whole segment", path)
+			}
+		}
+	}
+
+	if namespaces != 1 || names != 1 {
+		return fmt.Errorf("path %q must contain %s and %s exactly once",
+			path, SPIFFENamespacePlaceholder, SPIFFENamePlaceholder)
+	}
+	return nil
+}
@@ -131,6 +131,14 @@ func (s *JWTSigner) GenerateTokenFlatClaims(namespace, name, uid string, audienc
 	return token.SignedString(s.privateKey)
 }
 
+// SignClaims signs arbitrary claims with the signer's private key and key ID.
+// Use this for testing tokens with claims the other generators don't produce.
+func (s *JWTSigner) SignClaims(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = s.keyID
+	return token.SignedString(s.privateKey)
+}
+
 // ParseToken parses and validates a JWT token
 func (s *JWTSigner) ParseToken(tokenString string) (*TokenClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
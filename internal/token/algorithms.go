@@ -0,0 +1,119 @@
+package token
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"fmt"
+	"slices"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/holos-run/tokensmith/internal/config"
+)
+
+// signingAlgorithms returns the algorithms tokens from a cluster may be signed
+// with. An explicit allowlist takes precedence over the algorithms advertised
+// by discovery, which take precedence over all supported algorithms.
+func (v *JWKSValidator) signingAlgorithms(ctx context.Context, cluster *config.ClusterConfig) ([]jose.SignatureAlgorithm, error) {
+	if len(cluster.SigningAlgorithms) > 0 {
+		return cluster.SigningAlgorithms, nil
+	}
+
+	if cluster.Discovery {
+		doc, err := v.getDiscovery(ctx, cluster)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get discovery document: %w", err)
+		}
+		if len(doc.SigningAlgs) > 0 {
+			algs := make([]jose.SignatureAlgorithm, 0, len(doc.SigningAlgs))
+			for _, alg := range doc.SigningAlgs {
+				algs = append(algs, jose.SignatureAlgorithm(alg))
+			}
+			return algs, nil
+		}
+	}
+
+	return config.SupportedSigningAlgorithms, nil
+}
+
+// checkSigningAlgorithm verifies that the token is signed with one of the
+// allowed algorithms.
+func checkSigningAlgorithm(tok *jwt.JSONWebToken, allowed []jose.SignatureAlgorithm) error {
+	for _, header := range tok.Headers {
+		if !slices.Contains(allowed, jose.SignatureAlgorithm(header.Algorithm)) {
+			return fmt.Errorf("signing algorithm %s is not allowed", header.Algorithm)
+		}
+	}
+
+	return nil
+}
+
+// verificationKey returns the key from jwks that verifies a token with the
+// given header. The key must match the header's key ID and be usable with
+// the header's algorithm, so a key can't be used with an algorithm it wasn't
+// meant for.
+func verificationKey(jwks *jose.JSONWebKeySet, header jose.Header) (*jose.JSONWebKey, error) {
+	if header.KeyID == "" {
+		return nil, fmt.Errorf("token has no key ID")
+	}
+
+	keys := jwks.Key(header.KeyID)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no key with ID %q", header.KeyID)
+	}
+
+	alg := jose.SignatureAlgorithm(header.Algorithm)
+	var errs []error
+	for i := range keys {
+		err := checkKeyAlgorithm(&keys[i], alg)
+		if err == nil {
+			return &keys[i], nil
+		}
+		errs = append(errs, err)
+	}
+
+	return nil, fmt.Errorf("no key with ID %q can verify %s: %w", header.KeyID, alg, errs[0])
+}
+
+// checkKeyAlgorithm verifies that a JWK can be used to verify signatures
+// made with alg. Keys of SPIFFE trust bundles are usable only if they are
+// intended for JWT-SVIDs.
+func checkKeyAlgorithm(key *jose.JSONWebKey, alg jose.SignatureAlgorithm) error {
+	if key.Use != "" && key.Use != "sig" && key.Use != "jwt-svid" {
+		return fmt.Errorf("key use is %q", key.Use)
+	}
+	if key.Algorithm != "" && key.Algorithm != string(alg) {
+		return fmt.Errorf("key algorithm is %s", key.Algorithm)
+	}
+
+	switch pub := key.Key.(type) {
+	case *rsa.PublicKey:
+		switch alg {
+		case jose.RS256, jose.RS384, jose.RS512, jose.PS256, jose.PS384, jose.PS512:
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		curves := map[jose.SignatureAlgorithm]elliptic.Curve{
+			jose.ES256: elliptic.P256(),
+			jose.ES384: elliptic.P384(),
+			jose.ES512: elliptic.P521(),
+		}
+		if curve, ok := curves[alg]; ok {
+			if pub.Curve != curve {
+				return fmt.Errorf("key curve %s does not match", pub.Curve.Params().Name)
+			}
+			return nil
+		}
+	case ed25519.PublicKey:
+		if alg == jose.EdDSA {
+			return nil
+		}
+	default:
+		return fmt.Errorf("unsupported key type %T", key.Key)
+	}
+
+	return fmt.Errorf("key type %T does not match", key.Key)
+}
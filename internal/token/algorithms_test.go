@@ -0,0 +1,228 @@
+package token
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/holos-run/tokensmith/internal/config"
+)
+
+// signJOSE signs a service account token with the given algorithm and key.
+func signJOSE(t *testing.T, issuer string, alg jose.SignatureAlgorithm, key crypto.Signer, kid string) string {
+	t.Helper()
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", kid))
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	now := time.Now()
+	claims := jwt.Claims{
+		Issuer:   issuer,
+		Subject:  "system:serviceaccount:default:my-sa",
+		Audience: jwt.Audience{"tokensmith"},
+		Expiry:   jwt.NewNumericDate(now.Add(1 * time.Hour)),
+		IssuedAt: jwt.NewNumericDate(now),
+	}
+	k8sClaims := map[string]interface{}{
+		"kubernetes.io": map[string]interface{}{
+			"namespace": "default",
+			"serviceaccount": map[string]interface{}{
+				"name": "my-sa",
+				"uid":  "12345678-1234-1234-1234-123456789012",
+			},
+		},
+	}
+
+	token, err := jwt.Signed(signer).Claims(claims).Claims(k8sClaims).Serialize()
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return token
+}
+
+func TestJWKSValidator_SigningAlgorithms(t *testing.T) {
+	ctx := context.Background()
+	issuer := "https://kubernetes.default.svc.cluster.local"
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate ECDSA key: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+
+	jwks := &jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{
+			{Key: rsaKey.Public(), KeyID: "rsa", Use: "sig"},
+			{Key: ecKey.Public(), KeyID: "ec", Use: "sig"},
+			{Key: edKey.Public(), KeyID: "ed", Use: "sig"},
+			{Key: rsaKey.Public(), KeyID: "rsa-pinned", Algorithm: "RS256", Use: "sig"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		allowed []jose.SignatureAlgorithm
+		alg     jose.SignatureAlgorithm
+		key     crypto.Signer
+		kid     string
+		wantErr string
+	}{
+		{
+			name: "RS256 by default",
+			alg:  jose.RS256,
+			key:  rsaKey,
+			kid:  "rsa",
+		},
+		{
+			name: "PS256 by default",
+			alg:  jose.PS256,
+			key:  rsaKey,
+			kid:  "rsa",
+		},
+		{
+			name: "EdDSA by default",
+			alg:  jose.EdDSA,
+			key:  edKey,
+			kid:  "ed",
+		},
+		{
+			name:    "PS256 pinned",
+			allowed: []jose.SignatureAlgorithm{jose.PS256},
+			alg:     jose.PS256,
+			key:     rsaKey,
+			kid:     "rsa",
+		},
+		{
+			name:    "RS256 rejected when pinned to PS256",
+			allowed: []jose.SignatureAlgorithm{jose.PS256},
+			alg:     jose.RS256,
+			key:     rsaKey,
+			kid:     "rsa",
+			wantErr: "signing algorithm RS256 is not allowed",
+		},
+		{
+			name:    "ES256 signature with RSA key ID",
+			alg:     jose.ES256,
+			key:     ecKey,
+			kid:     "rsa",
+			wantErr: "can verify ES256",
+		},
+		{
+			name:    "EdDSA signature with ECDSA key ID",
+			alg:     jose.EdDSA,
+			key:     edKey,
+			kid:     "ec",
+			wantErr: "can verify EdDSA",
+		},
+		{
+			name:    "PS256 signature with key pinned to RS256",
+			alg:     jose.PS256,
+			key:     rsaKey,
+			kid:     "rsa-pinned",
+			wantErr: "key algorithm is RS256",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.ClustersConfig{
+				Clusters: []config.ClusterConfig{
+					{
+						Name:              "cluster",
+						Issuer:            issuer,
+						Audiences:         []string{"tokensmith"},
+						JWKSData:          jwks,
+						SigningAlgorithms: tt.allowed,
+					},
+				},
+			}
+			if err := cfg.Validate(); err != nil {
+				t.Fatalf("Invalid configuration: %v", err)
+			}
+			validator := NewJWKSValidator(cfg)
+
+			token := signJOSE(t, issuer, tt.alg, tt.key, tt.kid)
+			identity, err := validator.Validate(ctx, token)
+			if tt.wantErr != "" {
+				if err == nil {
+					t.Fatalf("Expected error containing %q, got nil", tt.wantErr)
+				}
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %q", tt.wantErr, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validation failed: %v", err)
+			}
+			if identity.Name != "my-sa" {
+				t.Errorf("Expected name 'my-sa', got %q", identity.Name)
+			}
+		})
+	}
+}
+
+func TestCheckKeyAlgorithm(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	p256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate ECDSA key: %v", err)
+	}
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		key     jose.JSONWebKey
+		alg     jose.SignatureAlgorithm
+		wantErr bool
+	}{
+		{name: "RSA with RS512", key: jose.JSONWebKey{Key: &rsaKey.PublicKey}, alg: jose.RS512},
+		{name: "RSA with PS384", key: jose.JSONWebKey{Key: &rsaKey.PublicKey}, alg: jose.PS384},
+		{name: "RSA with ES256", key: jose.JSONWebKey{Key: &rsaKey.PublicKey}, alg: jose.ES256, wantErr: true},
+		{name: "P-256 with ES256", key: jose.JSONWebKey{Key: &p256Key.PublicKey}, alg: jose.ES256},
+		{name: "P-256 with ES384", key: jose.JSONWebKey{Key: &p256Key.PublicKey}, alg: jose.ES384, wantErr: true},
+		{name: "P-256 with RS256", key: jose.JSONWebKey{Key: &p256Key.PublicKey}, alg: jose.RS256, wantErr: true},
+		{name: "Ed25519 with EdDSA", key: jose.JSONWebKey{Key: edPub}, alg: jose.EdDSA},
+		{name: "Ed25519 with ES256", key: jose.JSONWebKey{Key: edPub}, alg: jose.ES256, wantErr: true},
+		{name: "encryption key", key: jose.JSONWebKey{Key: &rsaKey.PublicKey, Use: "enc"}, alg: jose.RS256, wantErr: true},
+		{name: "matching key alg", key: jose.JSONWebKey{Key: &rsaKey.PublicKey, Algorithm: "PS256"}, alg: jose.PS256},
+		{name: "mismatched key alg", key: jose.JSONWebKey{Key: &rsaKey.PublicKey, Algorithm: "PS256"}, alg: jose.RS256, wantErr: true},
+		{name: "symmetric key", key: jose.JSONWebKey{Key: []byte("secret")}, alg: jose.RS256, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkKeyAlgorithm(&tt.key, tt.alg)
+			if tt.wantErr && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package token
 
 import (
+	"strings"
 	"sync"
 	"time"
 )
@@ -35,20 +36,28 @@ func NewCache() *Cache {
 // Get retrieves a token from the cache by workload service account UID.
 // Returns (token, true) if found and not expired, or ("", false) otherwise.
 func (c *Cache) Get(uid string) (string, bool) {
+	token, _, found := c.GetWithExpiration(uid)
+	return token, found
+}
+
+// GetWithExpiration retrieves a token and the expiration time it was stored
+// with. Returns (token, expiresAt, true) if found and not expired, or
+// ("", time.Time{}, false) otherwise.
+func (c *Cache) GetWithExpiration(uid string) (string, time.Time, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	entry, found := c.entries[uid]
 	if !found {
-		return "", false
+		return "", time.Time{}, false
 	}
 
 	// Check if expired
 	if time.Now().After(entry.expiresAt) {
-		return "", false
+		return "", time.Time{}, false
 	}
 
-	return entry.token, true
+	return entry.token, entry.expiresAt, true
 }
 
 // Set stores a token in the cache indexed by workload service account UID.
@@ -63,6 +72,18 @@ func (c *Cache) Set(uid string, token string, expiresAt time.Time) {
 	}
 }
 
+// DeletePrefix removes all tokens whose key starts with prefix.
+func (c *Cache) DeletePrefix(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+		}
+	}
+}
+
 // Stop gracefully shuts down the background garbage collection goroutine.
 // This should be called when the cache is no longer needed.
 // It is safe to call Stop() multiple times.
@@ -235,3 +235,24 @@ func TestCache_BackgroundCleanup(t *testing.T) {
 	cache.mu.RUnlock()
 	assert.False(t, found, "expired entry should be removed by background cleanup")
 }
+
+func TestCache_DeletePrefix(t *testing.T) {
+	cache := NewCache()
+	defer cache.Stop()
+
+	expiresAt := time.Now().Add(1 * time.Hour)
+	cache.Set("uid-1/default/sa-a", "token-1", expiresAt)
+	cache.Set("uid-1/default/sa-b", "token-2", expiresAt)
+	cache.Set("uid-2/default/sa-a", "token-3", expiresAt)
+
+	cache.DeletePrefix("uid-1/")
+
+	_, found := cache.Get("uid-1/default/sa-a")
+	assert.False(t, found)
+	_, found = cache.Get("uid-1/default/sa-b")
+	assert.False(t, found)
+
+	token, found := cache.Get("uid-2/default/sa-a")
+	assert.True(t, found)
+	assert.Equal(t, "token-3", token, "other UIDs should be kept")
+}
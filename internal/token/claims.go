@@ -0,0 +1,54 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/holos-run/tokensmith/internal/config"
+)
+
+// validateClaims validates the standard claims of a token at time now,
+// including the audience, against a cluster's claim validation profile.
+// A nil profile only checks exp, nbf and iat when present.
+func validateClaims(claims *jwt.Claims, audiences []string, profile *config.ClaimValidation, now time.Time) error {
+	leeway := profile.GetLeeway()
+	expected := jwt.Expected{
+		Time:        now,
+		AnyAudience: jwt.Audience(audiences),
+	}
+	if err := claims.ValidateWithLeeway(expected, leeway); err != nil {
+		return err
+	}
+
+	if profile == nil {
+		return nil
+	}
+
+	if claims.IssuedAt == nil && (profile.RequireIssuedAt || profile.MaxLifetime > 0 || profile.MaxAge > 0) {
+		return errors.New("token has no iat claim")
+	}
+	if claims.NotBefore == nil && profile.RequireNotBefore {
+		return errors.New("token has no nbf claim")
+	}
+
+	if profile.MaxLifetime > 0 {
+		if claims.Expiry == nil {
+			return errors.New("token has no exp claim")
+		}
+		lifetime := claims.Expiry.Time().Sub(claims.IssuedAt.Time())
+		if lifetime > profile.MaxLifetime {
+			return fmt.Errorf("token lifetime %s exceeds maximum %s", lifetime, profile.MaxLifetime)
+		}
+	}
+
+	if profile.MaxAge > 0 {
+		age := now.Sub(claims.IssuedAt.Time())
+		if age > profile.MaxAge+leeway {
+			return fmt.Errorf("token age %s exceeds maximum %s", age.Truncate(time.Second), profile.MaxAge)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package token
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4/jwt"
+	jwtv5 "github.com/golang-jwt/jwt/v5"
+	"github.com/holos-run/tokensmith/internal/config"
+	"github.com/holos-run/tokensmith/internal/testutil"
+)
+
+func TestValidateClaims(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *jwt.NumericDate {
+		return jwt.NewNumericDate(now.Add(d))
+	}
+
+	tests := []struct {
+		name    string
+		claims  jwt.Claims
+		profile *config.ClaimValidation
+		wantErr string
+	}{
+		{
+			name:   "default profile",
+			claims: jwt.Claims{Expiry: at(time.Hour), IssuedAt: at(0)},
+		},
+		{
+			name:   "default profile without iat",
+			claims: jwt.Claims{Expiry: at(time.Hour)},
+		},
+		{
+			name:   "expired within default leeway",
+			claims: jwt.Claims{Expiry: at(-30 * time.Second)},
+		},
+		{
+			name:    "expired beyond default leeway",
+			claims:  jwt.Claims{Expiry: at(-2 * time.Minute)},
+			wantErr: "expired",
+		},
+		{
+			name:    "expired within configured leeway",
+			claims:  jwt.Claims{Expiry: at(-2 * time.Minute)},
+			profile: &config.ClaimValidation{Leeway: 5 * time.Minute},
+		},
+		{
+			name:    "issued in the future within configured leeway",
+			claims:  jwt.Claims{Expiry: at(time.Hour), IssuedAt: at(3 * time.Minute), NotBefore: at(3 * time.Minute)},
+			profile: &config.ClaimValidation{Leeway: 5 * time.Minute},
+		},
+		{
+			name:    "wrong audience",
+			claims:  jwt.Claims{Expiry: at(time.Hour), Audience: jwt.Audience{"other"}},
+			wantErr: "audience",
+		},
+		{
+			name:    "required iat missing",
+			claims:  jwt.Claims{Expiry: at(time.Hour)},
+			profile: &config.ClaimValidation{RequireIssuedAt: true},
+			wantErr: "no iat claim",
+		},
+		{
+			name:    "required nbf missing",
+			claims:  jwt.Claims{Expiry: at(time.Hour), IssuedAt: at(0)},
+			profile: &config.ClaimValidation{RequireNotBefore: true},
+			wantErr: "no nbf claim",
+		},
+		{
+			name:    "lifetime within maximum",
+			claims:  jwt.Claims{Expiry: at(time.Hour), IssuedAt: at(0)},
+			profile: &config.ClaimValidation{MaxLifetime: time.Hour},
+		},
+		{
+			name:    "lifetime exceeds maximum",
+			claims:  jwt.Claims{Expiry: at(365 * 24 * time.Hour), IssuedAt: at(0)},
+			profile: &config.ClaimValidation{MaxLifetime: 24 * time.Hour},
+			wantErr: "exceeds maximum",
+		},
+		{
+			name:    "max lifetime requires exp",
+			claims:  jwt.Claims{IssuedAt: at(0)},
+			profile: &config.ClaimValidation{MaxLifetime: 24 * time.Hour},
+			wantErr: "no exp claim",
+		},
+		{
+			name:    "max lifetime requires iat",
+			claims:  jwt.Claims{Expiry: at(time.Hour)},
+			profile: &config.ClaimValidation{MaxLifetime: 24 * time.Hour},
+			wantErr: "no iat claim",
+		},
+		{
+			name:    "age within maximum",
+			claims:  jwt.Claims{Expiry: at(time.Hour), IssuedAt: at(-10 * time.Minute)},
+			profile: &config.ClaimValidation{MaxAge: 15 * time.Minute},
+		},
+		{
+			name:    "age exceeds maximum",
+			claims:  jwt.Claims{Expiry: at(time.Hour), IssuedAt: at(-20 * time.Minute)},
+			profile: &config.ClaimValidation{MaxAge: 15 * time.Minute},
+			wantErr: "token age",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := tt.claims
+			if claims.Audience == nil {
+				claims.Audience = jwt.Audience{"tokensmith"}
+			}
+
+			err := validateClaims(&claims, []string{"tokensmith"}, tt.profile, now)
+			if tt.wantErr != "" {
+				if err == nil {
+					t.Fatalf("Expected error containing %q, got nil", tt.wantErr)
+				}
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %q", tt.wantErr, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestJWKSValidator_SubjectMatch(t *testing.T) {
+	ctx := context.Background()
+	issuer := "https://kubernetes.default.svc.cluster.local"
+
+	signer, err := testutil.NewJWTSigner(issuer)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	cfg := &config.ClustersConfig{
+		Clusters: []config.ClusterConfig{
+			{
+				Name:      "cluster",
+				Issuer:    issuer,
+				Audiences: []string{"tokensmith"},
+				JWKSData:  createJWKS(signer.PublicKey(), signer.KeyID()),
+				ClaimValidation: &config.ClaimValidation{
+					RequireSubjectMatch: true,
+				},
+			},
+		},
+	}
+	validator := NewJWKSValidator(cfg)
+
+	tests := []struct {
+		name    string
+		subject string
+		wantErr bool
+	}{
+		{name: "matching subject", subject: "system:serviceaccount:default:my-sa"},
+		{name: "other service account", subject: "system:serviceaccount:kube-system:admin", wantErr: true},
+		{name: "missing subject", subject: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			token, err := signer.SignClaims(jwtv5.MapClaims{
+				"iss": issuer,
+				"sub": tt.subject,
+				"aud": []string{"tokensmith"},
+				"exp": now.Add(time.Hour).Unix(),
+				"iat": now.Unix(),
+				"kubernetes.io": map[string]interface{}{
+					"namespace": "default",
+					"serviceaccount": map[string]interface{}{
+						"name": "my-sa",
+						"uid":  "12345678-1234-1234-1234-123456789012",
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to sign token: %v", err)
+			}
+
+			_, err = validator.Validate(ctx, token)
+			if tt.wantErr && err == nil {
+				t.Error("Expected validation to fail, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Validation failed: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/holos-run/tokensmith/internal/config"
+)
+
+// CompositeValidator routes tokens to a validator by their unverified issuer
+// according to each cluster's validation mode. Clusters may verify tokens
+// locally with their JWKS, with the TokenReview API of their API server, or
+// both.
+type CompositeValidator struct {
+	config *config.ClustersConfig
+	jwks   TokenValidator
+
+	// reviewers holds TokenReview validators indexed by cluster name.
+	reviewers map[string]TokenValidator
+}
+
+// NewCompositeValidator creates a validator for the clusters in cfg that
+// verifies tokens for JWKS clusters with jwks. TokenReview validators must be
+// set with SetTokenReviewValidator for clusters that use TokenReview.
+func NewCompositeValidator(cfg *config.ClustersConfig, jwks TokenValidator) *CompositeValidator {
+	return &CompositeValidator{
+		config:    cfg,
+		jwks:      jwks,
+		reviewers: make(map[string]TokenValidator),
+	}
+}
+
+// SetTokenReviewValidator sets the TokenReview validator for the named cluster.
+func (c *CompositeValidator) SetTokenReviewValidator(cluster string, validator TokenValidator) {
+	c.reviewers[cluster] = validator
+}
+
+// Validate validates a token with the validators of the cluster matching its
+// issuer. Legacy tokens share an issuer across clusters and are validated
+// with validateLegacy.
+func (c *CompositeValidator) Validate(ctx context.Context, bearerToken string) (*ServiceAccountIdentity, error) {
+	claims, _, err := parseUnverifiedClaims(bearerToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Issuer == legacyIssuer {
+		return c.validateLegacy(ctx, bearerToken)
+	}
+
+	cluster, err := findCluster(c.config, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := c.validateCluster(ctx, cluster, bearerToken)
+	if err != nil {
+		return nil, err
+	}
+	identity.Cluster = cluster.Name
+	return identity, nil
+}
+
+// validateLegacy validates a legacy token. Legacy tokens share an issuer
+// across clusters, so they're sent to exactly one cluster: the JWKS validator
+// selects a cluster verifying legacy tokens with its key set by signing key,
+// and the token is then validated according to that cluster's validation
+// mode. If no key set has the signing key, the token goes to the single
+// TokenReview cluster accepting legacy tokens, if any.
+func (c *CompositeValidator) validateLegacy(ctx context.Context, bearerToken string) (*ServiceAccountIdentity, error) {
+	identity, err := c.jwks.Validate(ctx, bearerToken)
+	if err == nil {
+		cluster := c.config.FindByName(identity.Cluster)
+		if cluster == nil || !cluster.UsesTokenReview() {
+			return identity, nil
+		}
+		// Apply the cluster's TokenReview cross-check
+		identity, err = c.validateCluster(ctx, cluster, bearerToken)
+		if err != nil {
+			return nil, err
+		}
+		identity.Cluster = cluster.Name
+		return identity, nil
+	}
+	if !errors.Is(err, ErrLegacyToken) && !errors.Is(err, errUnknownLegacyKey) {
+		return nil, err
+	}
+
+	cluster := c.config.LegacyTokenReviewCluster()
+	if cluster == nil {
+		return nil, err
+	}
+	identity, err = c.validateCluster(ctx, cluster, bearerToken)
+	if err != nil {
+		return nil, fmt.Errorf("legacy token rejected by cluster %s: %w", cluster.Name, err)
+	}
+	identity.Cluster = cluster.Name
+	return identity, nil
+}
+
+// validateCluster validates a token with the validators of a cluster
+// according to its validation mode.
+func (c *CompositeValidator) validateCluster(ctx context.Context, cluster *config.ClusterConfig, bearerToken string) (*ServiceAccountIdentity, error) {
+	if !cluster.UsesTokenReview() {
+		return c.jwks.Validate(ctx, bearerToken)
+	}
+
+	reviewer, ok := c.reviewers[cluster.Name]
+	if !ok {
+		return nil, fmt.Errorf("no token review validator configured for cluster %s", cluster.Name)
+	}
+
+	if !cluster.UsesJWKS() {
+		return reviewer.Validate(ctx, bearerToken)
+	}
+
+	identity, err := c.jwks.Validate(ctx, bearerToken)
+	if err != nil {
+		// Only fall back when the keys are unavailable, so the policies
+		// enforced by JWKS validation can't be bypassed
+		if !errors.Is(err, ErrKeysUnavailable) {
+			return nil, err
+		}
+		return reviewer.Validate(ctx, bearerToken)
+	}
+
+	// Cross-check with the API server to detect revoked tokens
+	reviewed, err := reviewer.Validate(ctx, bearerToken)
+	if err != nil {
+		return nil, fmt.Errorf("token review cross-check failed: %w", err)
+	}
+	if reviewed.UID != identity.UID {
+		return nil, fmt.Errorf("token review cross-check failed: service account uid %s does not match %s",
+			reviewed.UID, identity.UID)
+	}
+
+	return identity, nil
+}
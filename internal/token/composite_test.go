@@ -0,0 +1,195 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/holos-run/tokensmith/internal/config"
+	"github.com/holos-run/tokensmith/internal/testutil"
+)
+
+// stubValidator returns a fixed result and counts calls.
+type stubValidator struct {
+	identity *ServiceAccountIdentity
+	err      error
+	calls    int
+}
+
+func (s *stubValidator) Validate(ctx context.Context, bearerToken string) (*ServiceAccountIdentity, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	identity := *s.identity
+	return &identity, nil
+}
+
+func TestCompositeValidator(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := &config.ClustersConfig{
+		Clusters: []config.ClusterConfig{
+			{Name: "jwks", Issuer: "https://jwks.example.com", Validation: config.ValidationJWKS},
+			{Name: "tokenreview", Issuer: "https://tokenreview.example.com", Validation: config.ValidationTokenReview},
+			{Name: "both", Issuer: "https://both.example.com", Validation: config.ValidationJWKSThenTokenReview},
+		},
+	}
+
+	identity := &ServiceAccountIdentity{Namespace: "default", Name: "my-sa", UID: "uid-1"}
+	other := &ServiceAccountIdentity{Namespace: "default", Name: "my-sa", UID: "uid-2"}
+	keysUnavailable := fmt.Errorf("%w: connection refused", ErrKeysUnavailable)
+
+	tests := []struct {
+		name          string
+		issuer        string
+		jwks          *stubValidator
+		reviewer      *stubValidator
+		expectError   bool
+		expectJWKS    int
+		expectReviews int
+	}{
+		{
+			name:       "jwks cluster",
+			issuer:     "https://jwks.example.com",
+			jwks:       &stubValidator{identity: identity},
+			reviewer:   &stubValidator{identity: identity},
+			expectJWKS: 1,
+		},
+		{
+			name:          "tokenreview cluster",
+			issuer:        "https://tokenreview.example.com",
+			jwks:          &stubValidator{identity: identity},
+			reviewer:      &stubValidator{identity: identity},
+			expectReviews: 1,
+		},
+		{
+			name:          "cross-check passes",
+			issuer:        "https://both.example.com",
+			jwks:          &stubValidator{identity: identity},
+			reviewer:      &stubValidator{identity: identity},
+			expectJWKS:    1,
+			expectReviews: 1,
+		},
+		{
+			name:          "cross-check detects revoked token",
+			issuer:        "https://both.example.com",
+			jwks:          &stubValidator{identity: identity},
+			reviewer:      &stubValidator{err: errors.New("token is not authenticated")},
+			expectError:   true,
+			expectJWKS:    1,
+			expectReviews: 1,
+		},
+		{
+			name:          "cross-check detects recreated service account",
+			issuer:        "https://both.example.com",
+			jwks:          &stubValidator{identity: identity},
+			reviewer:      &stubValidator{identity: other},
+			expectError:   true,
+			expectJWKS:    1,
+			expectReviews: 1,
+		},
+		{
+			name:          "falls back when keys are unavailable",
+			issuer:        "https://both.example.com",
+			jwks:          &stubValidator{err: keysUnavailable},
+			reviewer:      &stubValidator{identity: identity},
+			expectJWKS:    1,
+			expectReviews: 1,
+		},
+		{
+			name:        "no fallback when jwks validation rejects the token",
+			issuer:      "https://both.example.com",
+			jwks:        &stubValidator{err: errors.New("invalid claims")},
+			reviewer:    &stubValidator{identity: identity},
+			expectError: true,
+			expectJWKS:  1,
+		},
+		{
+			name:        "unknown issuer",
+			issuer:      "https://unknown.example.com",
+			jwks:        &stubValidator{identity: identity},
+			reviewer:    &stubValidator{identity: identity},
+			expectError: true,
+		},
+		{
+			name:       "legacy token",
+			issuer:     legacyIssuer,
+			jwks:       &stubValidator{identity: identity},
+			reviewer:   &stubValidator{identity: identity},
+			expectJWKS: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := NewCompositeValidator(cfg, tt.jwks)
+			validator.SetTokenReviewValidator("tokenreview", tt.reviewer)
+			validator.SetTokenReviewValidator("both", tt.reviewer)
+
+			signer, err := testutil.NewJWTSigner(tt.issuer)
+			if err != nil {
+				t.Fatalf("Failed to create signer: %v", err)
+			}
+			token, err := signer.GenerateToken("default", "my-sa", uuid.New().String(),
+				[]string{"tokensmith"}, time.Now().Add(1*time.Hour))
+			if err != nil {
+				t.Fatalf("Failed to generate token: %v", err)
+			}
+
+			result, err := validator.Validate(ctx, token)
+			if tt.expectError && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tt.expectError {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if result.UID != identity.UID {
+					t.Errorf("Expected uid %q, got %q", identity.UID, result.UID)
+				}
+				if cluster := cfg.FindByIssuer(tt.issuer); cluster != nil && result.Cluster != cluster.Name {
+					t.Errorf("Expected cluster %q, got %q", cluster.Name, result.Cluster)
+				}
+			}
+			if tt.jwks.calls != tt.expectJWKS {
+				t.Errorf("Expected %d JWKS validations, got %d", tt.expectJWKS, tt.jwks.calls)
+			}
+			if tt.reviewer.calls != tt.expectReviews {
+				t.Errorf("Expected %d TokenReviews, got %d", tt.expectReviews, tt.reviewer.calls)
+			}
+		})
+	}
+}
+
+func TestJWKSValidator_KeysUnavailable(t *testing.T) {
+	signer, err := testutil.NewJWTSigner("https://kubernetes.default.svc.cluster.local")
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	cfg := &config.ClustersConfig{
+		Clusters: []config.ClusterConfig{
+			{
+				Name:      "cluster",
+				Issuer:    "https://kubernetes.default.svc.cluster.local",
+				Audiences: []string{"tokensmith"},
+				JWKSURI:   "http://127.0.0.1:1/keys",
+			},
+		},
+	}
+	validator := NewJWKSValidator(cfg)
+
+	token, err := signer.GenerateToken("default", "my-sa", uuid.New().String(),
+		[]string{"tokensmith"}, time.Now().Add(1*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	if _, err := validator.Validate(context.Background(), token); !errors.Is(err, ErrKeysUnavailable) {
+		t.Errorf("Expected ErrKeysUnavailable, got %v", err)
+	}
+}
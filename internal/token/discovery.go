@@ -0,0 +1,101 @@
+package token
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/holos-run/tokensmith/internal/config"
+)
+
+// discoveryDocument holds the OIDC discovery metadata used for validation.
+type discoveryDocument struct {
+	Issuer      string   `json:"issuer"`
+	JWKSURI     string   `json:"jwks_uri"`
+	SigningAlgs []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// discoveryPath is the path of the OIDC discovery document relative to the
+// issuer.
+const discoveryPath = "/.well-known/openid-configuration"
+
+// discoveryURL returns the OIDC discovery URL for an issuer.
+func discoveryURL(issuer string) string {
+	return strings.TrimSuffix(issuer, "/") + discoveryPath
+}
+
+// discoveryURI returns the URI of the discovery document for a cluster. The
+// API server serves the document at its root whatever the path of the
+// issuer, so with workload access it's resolved as a relative path.
+func (v *JWKSValidator) discoveryURI(cluster *config.ClusterConfig) (string, error) {
+	if v.transportFor(cluster.Name) != nil {
+		return v.resolveURL(cluster.Name, discoveryPath)
+	}
+	return discoveryURL(cluster.Issuer), nil
+}
+
+// getDiscovery returns the discovery document for a cluster, using cache or
+// fetching as needed. Discovery documents are cached like key sets.
+func (v *JWKSValidator) getDiscovery(ctx context.Context, cluster *config.ClusterConfig) (*discoveryDocument, error) {
+	uri, err := v.discoveryURI(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := v.getDocument(ctx, uri, cluster.GetMaxStale(), v.discoveryFetcher(cluster, uri))
+	if err != nil {
+		return nil, err
+	}
+	return doc.discovery, nil
+}
+
+// refreshDiscovery fetches the discovery document for a cluster and updates
+// the cache.
+func (v *JWKSValidator) refreshDiscovery(ctx context.Context, cluster *config.ClusterConfig) (*cachedDocument, error) {
+	uri, err := v.discoveryURI(cluster)
+	if err != nil {
+		return nil, err
+	}
+	return v.refreshDocument(ctx, uri, v.discoveryFetcher(cluster, uri))
+}
+
+// discoveryFetcher returns a documentFetcher for the discovery document of a
+// cluster at a URI.
+func (v *JWKSValidator) discoveryFetcher(cluster *config.ClusterConfig, uri string) documentFetcher {
+	return func(ctx context.Context) (*cachedDocument, time.Duration, error) {
+		doc, ttl, err := v.fetchDiscovery(ctx, cluster, uri)
+		if err != nil {
+			return nil, 0, err
+		}
+		return &cachedDocument{discovery: doc}, ttl, nil
+	}
+}
+
+// fetchDiscovery fetches and verifies the discovery document for a cluster
+// and returns it with its cache lifetime.
+func (v *JWKSValidator) fetchDiscovery(ctx context.Context, cluster *config.ClusterConfig, uri string) (*discoveryDocument, time.Duration, error) {
+	issuer := cluster.Issuer
+
+	f, err := v.fetcherFor(cluster)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var doc discoveryDocument
+	header, err := f.fetchJSON(ctx, uri, &doc)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch discovery document: %w", err)
+	}
+
+	// The issuer must match exactly to prevent one issuer from impersonating another
+	if doc.Issuer != issuer {
+		return nil, 0, fmt.Errorf("discovery issuer %q does not match configured issuer %q", doc.Issuer, issuer)
+	}
+
+	if doc.JWKSURI == "" {
+		return nil, 0, fmt.Errorf("discovery document for %s has no jwks_uri", issuer)
+	}
+
+	return &doc, cacheTTL(header), nil
+}
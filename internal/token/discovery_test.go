@@ -0,0 +1,235 @@
+package token
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/holos-run/tokensmith/internal/config"
+	"github.com/holos-run/tokensmith/internal/testutil"
+)
+
+// newDiscoveryServer starts a test OIDC issuer serving a discovery document
+// and JWKS. The discovery document is produced by discovery so tests can
+// tamper with it.
+func newDiscoveryServer(t *testing.T, discovery func(issuer string) discoveryDocument) (*httptest.Server, *testutil.JWTSigner) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	signer, err := testutil.NewJWTSigner(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, discovery(server.URL))
+	})
+	mux.HandleFunc("/openid/v1/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, createJWKS(signer.PublicKey(), signer.KeyID()))
+	})
+
+	return server, signer
+}
+
+func TestJWKSValidator_Discovery(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		discovery func(issuer string) discoveryDocument
+		wantErr   bool
+	}{
+		{
+			name: "valid discovery document",
+			discovery: func(issuer string) discoveryDocument {
+				return discoveryDocument{
+					Issuer:      issuer,
+					JWKSURI:     issuer + "/openid/v1/jwks",
+					SigningAlgs: []string{"RS256"},
+				}
+			},
+			wantErr: false,
+		},
+		{
+			name: "issuer mismatch",
+			discovery: func(issuer string) discoveryDocument {
+				return discoveryDocument{
+					Issuer:      "https://attacker.example.com",
+					JWKSURI:     issuer + "/openid/v1/jwks",
+					SigningAlgs: []string{"RS256"},
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing jwks_uri",
+			discovery: func(issuer string) discoveryDocument {
+				return discoveryDocument{
+					Issuer: issuer,
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "signing algorithm not advertised",
+			discovery: func(issuer string) discoveryDocument {
+				return discoveryDocument{
+					Issuer:      issuer,
+					JWKSURI:     issuer + "/openid/v1/jwks",
+					SigningAlgs: []string{"ES256"},
+				}
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, signer := newDiscoveryServer(t, tt.discovery)
+
+			cfg := &config.ClustersConfig{
+				Clusters: []config.ClusterConfig{
+					{
+						Name:      "cluster",
+						Issuer:    server.URL,
+						Audiences: []string{"tokensmith"},
+						Discovery: true,
+					},
+				},
+			}
+			validator := NewJWKSValidator(cfg)
+
+			token, err := signer.GenerateToken(
+				"default",
+				"my-sa",
+				uuid.New().String(),
+				[]string{"tokensmith"},
+				time.Now().Add(1*time.Hour),
+			)
+			if err != nil {
+				t.Fatalf("Failed to generate token: %v", err)
+			}
+
+			identity, err := validator.Validate(ctx, token)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Validation failed: %v", err)
+			}
+			if identity.Name != "my-sa" {
+				t.Errorf("Expected name 'my-sa', got %q", identity.Name)
+			}
+		})
+	}
+}
+
+func TestJWKSValidator_DiscoveryCache(t *testing.T) {
+	ctx := context.Background()
+
+	var fetches atomic.Int32
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	signer, err := testutil.NewJWTSigner(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		// Hold the response so concurrent callers overlap
+		time.Sleep(50 * time.Millisecond)
+		w.Header().Set("Cache-Control", "max-age=300")
+		writeJSON(w, discoveryDocument{
+			Issuer:  server.URL,
+			JWKSURI: server.URL + "/openid/v1/jwks",
+		})
+	})
+	mux.HandleFunc("/openid/v1/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, createJWKS(signer.PublicKey(), signer.KeyID()))
+	})
+
+	cfg := &config.ClustersConfig{
+		Clusters: []config.ClusterConfig{
+			{
+				Name:      "cluster",
+				Issuer:    server.URL,
+				Audiences: []string{"tokensmith"},
+				Discovery: true,
+			},
+		},
+	}
+	validator := NewJWKSValidator(cfg)
+
+	token, err := signer.GenerateToken("default", "my-sa", uuid.New().String(),
+		[]string{"tokensmith"}, time.Now().Add(1*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	if _, err := validator.Validate(ctx, token); err != nil {
+		t.Fatalf("Validation failed: %v", err)
+	}
+
+	discoveryURI := discoveryURL(server.URL)
+	validator.mu.Lock()
+	cached := validator.cache[discoveryURI]
+	validator.mu.Unlock()
+	if cached == nil {
+		t.Fatal("Expected discovery document to be cached")
+	}
+	if ttl := cached.expiresAt.Sub(cached.fetchedAt); ttl != 300*time.Second {
+		t.Errorf("Expected discovery cache TTL of 300s from Cache-Control, got %v", ttl)
+	}
+
+	// Expire the discovery document and validate concurrently
+	validator.mu.Lock()
+	validator.cache[discoveryURI].expiresAt = time.Now().Add(-time.Second)
+	validator.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := validator.Validate(ctx, token); err != nil {
+				t.Errorf("Validation failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The expired document is served while one shared refresh runs in the
+	// background
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		validator.mu.RLock()
+		fresh := time.Now().Before(validator.cache[discoveryURI].expiresAt)
+		validator.mu.RUnlock()
+		if fresh {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected discovery document to be refreshed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("Expected concurrent refreshes to share one discovery fetch, got %d fetches in total", got)
+	}
+}
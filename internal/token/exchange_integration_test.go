@@ -129,7 +129,7 @@ func TestTokenExchangeFlow(t *testing.T) {
 	})
 
 	// Create validator and exchanger
-	validator := NewValidator(workloadClient)
+	validator := NewValidator(workloadClient, nil)
 
 	expirationSeconds := int64(3600)
 	exchanger := NewExchanger(managementClient, ExchangeConfig{
@@ -353,7 +353,7 @@ func TestTokenExchangeServiceAccountNotFound(t *testing.T) {
 	managementClient := fake.NewSimpleClientset()
 
 	// Create validator and exchanger
-	validator := NewValidator(workloadClient)
+	validator := NewValidator(workloadClient, nil)
 
 	expirationSeconds := int64(3600)
 	exchanger := NewExchanger(managementClient, ExchangeConfig{
@@ -408,7 +408,7 @@ func TestTokenValidationFails(t *testing.T) {
 	})
 
 	// Create validator
-	validator := NewValidator(workloadClient)
+	validator := NewValidator(workloadClient, nil)
 
 	// Execute validation (should fail)
 	_, err := validator.Validate(ctx, "invalid-or-expired-token")
@@ -516,7 +516,7 @@ func TestTokenExpirationPreserved(t *testing.T) {
 	})
 
 	// Create validator and exchanger
-	validator := NewValidator(workloadClient)
+	validator := NewValidator(workloadClient, nil)
 
 	expirationSeconds := int64(7200) // 2 hours
 	exchanger := NewExchanger(managementClient, ExchangeConfig{
@@ -659,7 +659,7 @@ func TestTokenExchangeCache(t *testing.T) {
 	})
 
 	// Create validator and exchanger
-	validator := NewValidator(workloadClient)
+	validator := NewValidator(workloadClient, nil)
 	expirationSeconds := int64(3600)
 	exchanger := NewExchanger(managementClient, ExchangeConfig{
 		Audiences:         audiences,
@@ -861,7 +861,7 @@ func TestTokenExchangeCacheDifferentUIDs(t *testing.T) {
 	})
 
 	// Create validator and exchanger
-	validator := NewValidator(workloadClient)
+	validator := NewValidator(workloadClient, nil)
 	expirationSeconds := int64(3600)
 	exchanger := NewExchanger(managementClient, ExchangeConfig{
 		Audiences:         audiences,
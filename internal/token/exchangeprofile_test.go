@@ -0,0 +1,212 @@
+package token
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/holos-run/tokensmith/internal/config"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestExchanger_ExchangeProfiles(t *testing.T) {
+	ctx := context.Background()
+
+	serviceAccounts := []runtime.Object{
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "vault-reader", Namespace: "tenant-a"}},
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "deployer", Namespace: "tenant-a"}},
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "deployer", Namespace: "tenant-b"}},
+	}
+	managementClient := fake.NewSimpleClientset(serviceAccounts...)
+
+	specs := make(map[string]authenticationv1.TokenRequestSpec)
+	managementClient.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+		createAction := action.(k8stesting.CreateActionImpl)
+		tokenReq := createAction.GetObject().(*authenticationv1.TokenRequest)
+		specs[action.GetNamespace()+"/"+createAction.Name] = tokenReq.Spec
+		tokenReq.Status = authenticationv1.TokenRequestStatus{
+			Token:               "management-token",
+			ExpirationTimestamp: metav1.Time{Time: time.Now().Add(time.Hour)},
+		}
+		return true, tokenReq, nil
+	})
+
+	expiration := int64(3600)
+	exchanger := NewExchanger(managementClient, ExchangeConfig{
+		ExpirationSeconds: &expiration,
+		Profiles: []config.ExchangeProfile{
+			{
+				Name:           "vault",
+				ServiceAccount: "vault-*",
+				Audiences:      []string{"vault"},
+				Expiration:     15 * time.Minute,
+			},
+			{
+				Name:       "tenant-a-prod",
+				Clusters:   []string{"tenant-a-prod"},
+				Namespace:  "tenant-a",
+				Expiration: 2 * time.Hour,
+				BoundObjectRef: &config.BoundObjectRef{
+					Kind: "Secret",
+					Name: "deployer-binding",
+				},
+			},
+		},
+	})
+
+	tests := []struct {
+		name               string
+		identity           *ServiceAccountIdentity
+		expectedProfile    string
+		expectedAudiences  []string
+		expectedExpiration int64
+		expectedBoundKind  string
+	}{
+		{
+			name:               "service account selector",
+			identity:           &ServiceAccountIdentity{Cluster: "tenant-a-prod", Namespace: "tenant-a", Name: "vault-reader", UID: "uid-1"},
+			expectedProfile:    "vault",
+			expectedAudiences:  []string{"vault"},
+			expectedExpiration: 900,
+		},
+		{
+			name:               "cluster and namespace selectors with bound object",
+			identity:           &ServiceAccountIdentity{Cluster: "tenant-a-prod", Namespace: "tenant-a", Name: "deployer", UID: "uid-2"},
+			expectedProfile:    "tenant-a-prod",
+			expectedAudiences:  []string{"https://kubernetes.default.svc"},
+			expectedExpiration: 7200,
+			expectedBoundKind:  "Secret",
+		},
+		{
+			name:               "no matching profile",
+			identity:           &ServiceAccountIdentity{Cluster: "tenant-b", Namespace: "tenant-b", Name: "deployer", UID: "uid-3"},
+			expectedAudiences:  []string{"https://kubernetes.default.svc"},
+			expectedExpiration: 3600,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, err := exchanger.ExchangeWithMetadata(ctx, tt.identity)
+			if err != nil {
+				t.Fatalf("Exchange failed: %v", err)
+			}
+			if metadata.Profile != tt.expectedProfile {
+				t.Errorf("Expected profile %q, got %q", tt.expectedProfile, metadata.Profile)
+			}
+
+			spec, ok := specs[tt.identity.Namespace+"/"+tt.identity.Name]
+			if !ok {
+				t.Fatal("Expected a TokenRequest")
+			}
+			if !slices.Equal(spec.Audiences, tt.expectedAudiences) {
+				t.Errorf("Expected audiences %v, got %v", tt.expectedAudiences, spec.Audiences)
+			}
+			if spec.ExpirationSeconds == nil || *spec.ExpirationSeconds != tt.expectedExpiration {
+				t.Errorf("Expected expiration of %d seconds, got %v", tt.expectedExpiration, spec.ExpirationSeconds)
+			}
+
+			if tt.expectedBoundKind == "" {
+				if spec.BoundObjectRef != nil {
+					t.Errorf("Expected no bound object, got %+v", spec.BoundObjectRef)
+				}
+				return
+			}
+			if spec.BoundObjectRef == nil {
+				t.Fatal("Expected a bound object")
+			}
+			if spec.BoundObjectRef.Kind != tt.expectedBoundKind || spec.BoundObjectRef.APIVersion != "v1" {
+				t.Errorf("Expected bound %s in v1, got %s in %s",
+					tt.expectedBoundKind, spec.BoundObjectRef.Kind, spec.BoundObjectRef.APIVersion)
+			}
+		})
+	}
+}
+
+func TestClustersConfig_ValidateExchangeProfiles(t *testing.T) {
+	tests := []struct {
+		name        string
+		profiles    []config.ExchangeProfile
+		expectError bool
+	}{
+		{
+			name: "valid",
+			profiles: []config.ExchangeProfile{
+				{Name: "vault", Clusters: []string{"cluster"}, ServiceAccount: "vault-*", Audiences: []string{"vault"}, Expiration: 15 * time.Minute},
+				{Name: "bound", Namespace: "tenant-*", BoundObjectRef: &config.BoundObjectRef{Kind: "Pod", Name: "runner"}},
+			},
+		},
+		{
+			name:        "missing name",
+			profiles:    []config.ExchangeProfile{{Audiences: []string{"vault"}}},
+			expectError: true,
+		},
+		{
+			name:        "duplicate name",
+			profiles:    []config.ExchangeProfile{{Name: "vault"}, {Name: "vault"}},
+			expectError: true,
+		},
+		{
+			name:        "unknown cluster",
+			profiles:    []config.ExchangeProfile{{Name: "vault", Clusters: []string{"other"}}},
+			expectError: true,
+		},
+		{
+			name:        "invalid glob",
+			profiles:    []config.ExchangeProfile{{Name: "vault", ServiceAccount: "vault-["}},
+			expectError: true,
+		},
+		{
+			name:        "expiration below TokenRequest minimum",
+			profiles:    []config.ExchangeProfile{{Name: "short", Expiration: time.Minute}},
+			expectError: true,
+		},
+		{
+			name:        "unsupported bound object kind",
+			profiles:    []config.ExchangeProfile{{Name: "bound", BoundObjectRef: &config.BoundObjectRef{Kind: "ConfigMap", Name: "runner"}}},
+			expectError: true,
+		},
+		{
+			name:        "audience accepted by workload cluster",
+			profiles:    []config.ExchangeProfile{{Name: "loop", Audiences: []string{"tokensmith"}}},
+			expectError: true,
+		},
+		{
+			name:        "bound object without name",
+			profiles:    []config.ExchangeProfile{{Name: "bound", BoundObjectRef: &config.BoundObjectRef{Kind: "Secret"}}},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.ClustersConfig{
+				Clusters: []config.ClusterConfig{
+					{
+						Name:      "cluster",
+						Issuer:    "https://cluster.example.com",
+						Audiences: []string{"tokensmith"},
+						JWKSURI:   "https://cluster.example.com/openid/v1/jwks",
+					},
+				},
+				ExchangeProfiles: tt.profiles,
+			}
+			err := cfg.Validate()
+			if tt.expectError && err == nil {
+				t.Error("Expected validation error")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
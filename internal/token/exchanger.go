@@ -2,11 +2,14 @@ package token
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/holos-run/tokensmith/internal/config"
 	authenticationv1 "k8s.io/api/authentication/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -19,8 +22,31 @@ type ExchangeConfig struct {
 	// ExpirationSeconds is the requested token expiration in seconds.
 	// If not specified, defaults to 1 hour (3600 seconds).
 	ExpirationSeconds *int64
+
+	// IdentityMappings map workload identities to management cluster service
+	// accounts. If empty, identities exchange for the service account with
+	// the same namespace and name.
+	IdentityMappings []config.IdentityMapping
+
+	// Clusters is the workload cluster configuration. If set, identities may
+	// only exchange into the management namespaces allowed for their cluster.
+	Clusters *config.ClustersConfig
+
+	// RequireTrustedSubjects requires management service accounts to list
+	// the workload identities allowed to exchange for them in the
+	// TrustedSubjectsAnnotation.
+	RequireTrustedSubjects bool
+
+	// Profiles set the audiences, expiration and bound object of tokens for
+	// the exchanges they match, overriding Audiences and ExpirationSeconds.
+	// The first matching profile is used.
+	Profiles []config.ExchangeProfile
 }
 
+// ErrExchangeDenied is returned when policy forbids exchanging an identity for
+// a management cluster token.
+var ErrExchangeDenied = errors.New("token exchange denied")
+
 // Exchanger exchanges tokens using the Kubernetes TokenRequest API.
 // It caches tokens to avoid redundant CreateToken API calls for the same
 // workload service account identity.
@@ -54,62 +80,101 @@ func NewExchanger(client kubernetes.Interface, config ExchangeConfig) *Exchanger
 // Exchange exchanges a validated service account identity for a new token
 // in the management cluster.
 //
-// The service account with the same namespace and name must exist in the
-// management cluster. RBAC policies control what the service account can access.
+// The identity is mapped to a management cluster service account with the
+// configured identity mappings. By default the service account with the same
+// namespace and name must exist in the management cluster. RBAC policies
+// control what the service account can access.
 //
-// This method uses an in-memory cache indexed by the workload service account UID.
+// This method uses an in-memory cache indexed by the workload cluster and
+// service account UID, the target service account, and the requested audiences
+// and expiration.
 // If a valid cached token exists, it is returned immediately without calling the
 // Kubernetes API. This significantly reduces API load for repeated requests from
 // the same workload identity (e.g., External Secrets Operator polling).
 func (e *Exchanger) Exchange(ctx context.Context, identity *ServiceAccountIdentity) (string, error) {
-	// Try cache first - index by workload service account UID
-	cacheKey := string(identity.UID)
-	if token, found := e.cache.Get(cacheKey); found {
-		return token, nil
-	}
-
-	// Cache miss - proceed with token creation
-	// Verify service account exists in management cluster
-	sa, err := e.client.CoreV1().ServiceAccounts(identity.Namespace).Get(ctx, identity.Name, metav1.GetOptions{})
+	metadata, err := e.ExchangeWithMetadata(ctx, identity)
 	if err != nil {
-		return "", fmt.Errorf("service account %s/%s not found in management cluster: %w",
-			identity.Namespace, identity.Name, err)
+		return "", err
 	}
+	return metadata.Token, nil
+}
 
-	// Create TokenRequest
-	tokenRequest := &authenticationv1.TokenRequest{
-		Spec: authenticationv1.TokenRequestSpec{
-			Audiences:         e.config.Audiences,
-			ExpirationSeconds: e.config.ExpirationSeconds,
-		},
+// exchangeCacheKey returns the cache key for an identity exchanged for a
+// token of the management service account namespace/name with the given
+// audiences, expiration and bound object. The source cluster is part of the
+// key because UIDs are only unique within a cluster, and the target service
+// account, after identity mappings, because identities mapped from OIDC claims
+// share a UID across every service account their claims may map to.
+func exchangeCacheKey(identity *ServiceAccountIdentity, namespace, name string, audiences []string, expirationSeconds int64, boundObjectRef *authenticationv1.BoundObjectReference) string {
+	var bound []string
+	if boundObjectRef != nil {
+		bound = []string{boundObjectRef.Kind, boundObjectRef.Name, string(boundObjectRef.UID)}
 	}
+	return fmt.Sprintf("%s%q/%q/%q/%d/%q", workloadCacheKeyPrefix(identity.Cluster, identity.UID),
+		namespace, name, audiences, expirationSeconds, bound)
+}
 
-	// Call Kubernetes API to create token
-	result, err := e.client.CoreV1().ServiceAccounts(identity.Namespace).CreateToken(
-		ctx,
-		identity.Name,
-		tokenRequest,
-		metav1.CreateOptions{},
-	)
-	if err != nil {
-		return "", fmt.Errorf("failed to create token for service account %s/%s: %w",
-			identity.Namespace, identity.Name, err)
+// workloadCacheKeyPrefix returns the prefix shared by the cache keys of a
+// workload service account. Values are quoted so keys are unambiguous.
+func workloadCacheKeyPrefix(cluster, uid string) string {
+	return fmt.Sprintf("%q/%q/", cluster, uid)
+}
+
+// Evict removes the cached tokens of a workload service account in a
+// cluster, e.g. after the service account was deleted.
+func (e *Exchanger) Evict(cluster, uid string) {
+	e.cache.DeletePrefix(workloadCacheKeyPrefix(cluster, uid))
+}
+
+// checkNamespace verifies that an identity may exchange into a management
+// cluster namespace according to the configuration of its cluster.
+func (e *Exchanger) checkNamespace(identity *ServiceAccountIdentity, namespace string) error {
+	if e.config.Clusters == nil {
+		return nil
 	}
 
-	// Extract token from result
-	token := result.Status.Token
-	if token == "" {
-		return "", fmt.Errorf("received empty token from TokenRequest API")
+	cluster := e.config.Clusters.FindByName(identity.Cluster)
+	if cluster == nil {
+		return fmt.Errorf("%w: unknown cluster %q", ErrExchangeDenied, identity.Cluster)
+	}
+	if !cluster.AllowsManagementNamespace(namespace) {
+		return fmt.Errorf("%w: cluster %s may not exchange into management namespace %s",
+			ErrExchangeDenied, cluster.Name, namespace)
 	}
+	return nil
+}
 
-	// Log token metadata (for debugging, not the actual token)
-	_ = sa.UID // Service account UID for audit logging
+// tokenSpec returns the audiences, expiration and bound object of tokens an
+// identity exchanges for in a management service account, using the first
+// exchange profile that matches. Without a matching profile the profile is
+// nil and the configured defaults are used.
+func (e *Exchanger) tokenSpec(identity *ServiceAccountIdentity, namespace, name string) (profile *config.ExchangeProfile, audiences []string, expirationSeconds int64, boundObjectRef *authenticationv1.BoundObjectReference) {
+	audiences, expirationSeconds = e.config.Audiences, *e.config.ExpirationSeconds
 
-	// Store in cache before returning
-	expiresAt := result.Status.ExpirationTimestamp.Time
-	e.cache.Set(cacheKey, token, expiresAt)
+	for i := range e.config.Profiles {
+		if !e.config.Profiles[i].Matches(identity.Cluster, namespace, name) {
+			continue
+		}
+
+		profile = &e.config.Profiles[i]
+		if len(profile.Audiences) > 0 {
+			audiences = profile.Audiences
+		}
+		if profile.Expiration > 0 {
+			expirationSeconds = int64(profile.Expiration / time.Second)
+		}
+		if ref := profile.BoundObjectRef; ref != nil {
+			boundObjectRef = &authenticationv1.BoundObjectReference{
+				Kind:       ref.Kind,
+				APIVersion: ref.GetAPIVersion(),
+				Name:       ref.Name,
+				UID:        types.UID(ref.UID),
+			}
+		}
+		break
+	}
 
-	return token, nil
+	return profile, audiences, expirationSeconds, boundObjectRef
 }
 
 // ExchangeWithMetadata exchanges a token and returns both the token and metadata.
@@ -119,52 +184,98 @@ type TokenMetadata struct {
 	ServiceAccount    string
 	ExpirationTime    time.Time
 	ServiceAccountUID string
+
+	// Profile is the name of the exchange profile the token was issued with,
+	// or empty if no profile matched.
+	Profile string
 }
 
 // ExchangeWithMetadata exchanges a token and returns detailed metadata.
 // Like Exchange, this method uses caching to avoid redundant API calls.
 func (e *Exchanger) ExchangeWithMetadata(ctx context.Context, identity *ServiceAccountIdentity) (*TokenMetadata, error) {
-	// Try cache first - index by workload service account UID
-	cacheKey := string(identity.UID)
-	if token, found := e.cache.Get(cacheKey); found {
-		// For cached tokens, we need to compute expiration time
-		// Since we don't store metadata in cache, we'll use the configured expiration
-		expirationTime := time.Now().Add(time.Duration(*e.config.ExpirationSeconds) * time.Second)
+	// Map the workload identity to a management cluster service account
+	namespace, name, err := mapIdentity(e.config.IdentityMappings, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	// Enforce the management namespaces of the source cluster before any
+	// token is created
+	if err := e.checkNamespace(identity, namespace); err != nil {
+		return nil, err
+	}
+
+	// Select the token audiences, expiration and bound object
+	profile, audiences, expirationSeconds, boundObjectRef := e.tokenSpec(identity, namespace, name)
+	var profileName string
+	if profile != nil {
+		profileName = profile.Name
+	}
+
+	// Try cache first - index by workload identity and target service account
+	cacheKey := exchangeCacheKey(identity, namespace, name, audiences, expirationSeconds, boundObjectRef)
+	if token, expiresAt, found := e.cache.GetWithExpiration(cacheKey); found {
 		return &TokenMetadata{
 			Token:             token,
-			Namespace:         identity.Namespace,
-			ServiceAccount:    identity.Name,
-			ExpirationTime:    expirationTime,
+			Namespace:         namespace,
+			ServiceAccount:    name,
+			ExpirationTime:    expiresAt,
 			ServiceAccountUID: string(identity.UID),
+			Profile:           profileName,
 		}, nil
 	}
 
 	// Cache miss - proceed with token creation
 	// Verify service account exists in management cluster
-	sa, err := e.client.CoreV1().ServiceAccounts(identity.Namespace).Get(ctx, identity.Name, metav1.GetOptions{})
+	sa, err := e.client.CoreV1().ServiceAccounts(namespace).Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("service account %s/%s not found in management cluster: %w",
-			identity.Namespace, identity.Name, err)
+			namespace, name, err)
+	}
+
+	// The service account must trust the workload identity
+	if e.config.RequireTrustedSubjects {
+		if err := checkTrustedSubjects(sa, identity); err != nil {
+			return nil, err
+		}
+	}
+
+	// The service account may override the token audiences and lifetime
+	audiences, expirationSeconds, err = tokenOverrides(sa, audiences, expirationSeconds)
+	if err != nil {
+		return nil, err
+	}
+
+	// Exchanged tokens must not be accepted by a workload cluster, otherwise
+	// they could be exchanged again
+	if e.config.Clusters != nil {
+		for _, aud := range audiences {
+			if cluster := e.config.Clusters.FindByAudience(aud); cluster != nil {
+				return nil, fmt.Errorf("%w: audience %q of service account %s/%s is accepted by workload cluster %s",
+					ErrExchangeDenied, aud, namespace, name, cluster.Name)
+			}
+		}
 	}
 
 	// Create TokenRequest
 	tokenRequest := &authenticationv1.TokenRequest{
 		Spec: authenticationv1.TokenRequestSpec{
-			Audiences:         e.config.Audiences,
-			ExpirationSeconds: e.config.ExpirationSeconds,
+			Audiences:         audiences,
+			ExpirationSeconds: &expirationSeconds,
+			BoundObjectRef:    boundObjectRef,
 		},
 	}
 
 	// Call Kubernetes API to create token
-	result, err := e.client.CoreV1().ServiceAccounts(identity.Namespace).CreateToken(
+	result, err := e.client.CoreV1().ServiceAccounts(namespace).CreateToken(
 		ctx,
-		identity.Name,
+		name,
 		tokenRequest,
 		metav1.CreateOptions{},
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create token for service account %s/%s: %w",
-			identity.Namespace, identity.Name, err)
+			namespace, name, err)
 	}
 
 	// Extract token from result
@@ -179,9 +290,10 @@ func (e *Exchanger) ExchangeWithMetadata(ctx context.Context, identity *ServiceA
 
 	return &TokenMetadata{
 		Token:             token,
-		Namespace:         identity.Namespace,
-		ServiceAccount:    identity.Name,
+		Namespace:         namespace,
+		ServiceAccount:    name,
 		ExpirationTime:    result.Status.ExpirationTimestamp.Time,
 		ServiceAccountUID: string(sa.UID),
+		Profile:           profileName,
 	}, nil
 }
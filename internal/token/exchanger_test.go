@@ -0,0 +1,269 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/holos-run/tokensmith/internal/config"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestExchangeCacheKey(t *testing.T) {
+	identity := &ServiceAccountIdentity{Cluster: "cluster-a", Namespace: "default", Name: "my-sa", UID: "uid-1"}
+	audiences := []string{"https://kubernetes.default.svc"}
+	key := exchangeCacheKey(identity, "default", "my-sa", audiences, 3600, nil)
+
+	tests := []struct {
+		name              string
+		identity          ServiceAccountIdentity
+		namespace         string
+		serviceAccount    string
+		audiences         []string
+		expirationSeconds int64
+		boundObjectRef    *authenticationv1.BoundObjectReference
+	}{
+		{
+			name:              "other cluster",
+			identity:          ServiceAccountIdentity{Cluster: "cluster-b", Namespace: "default", Name: "my-sa", UID: "uid-1"},
+			namespace:         "default",
+			serviceAccount:    "my-sa",
+			audiences:         audiences,
+			expirationSeconds: 3600,
+		},
+		{
+			name:              "other target service account",
+			identity:          *identity,
+			namespace:         "default",
+			serviceAccount:    "other-sa",
+			audiences:         audiences,
+			expirationSeconds: 3600,
+		},
+		{
+			name:              "other target namespace",
+			identity:          *identity,
+			namespace:         "tenant-a",
+			serviceAccount:    "my-sa",
+			audiences:         audiences,
+			expirationSeconds: 3600,
+		},
+		{
+			name:              "other audiences",
+			identity:          *identity,
+			namespace:         "default",
+			serviceAccount:    "my-sa",
+			audiences:         []string{"https://kubernetes.default.svc", "vault"},
+			expirationSeconds: 3600,
+		},
+		{
+			name:              "other expiration",
+			identity:          *identity,
+			namespace:         "default",
+			serviceAccount:    "my-sa",
+			audiences:         audiences,
+			expirationSeconds: 600,
+		},
+		{
+			name:              "bound object",
+			identity:          *identity,
+			namespace:         "default",
+			serviceAccount:    "my-sa",
+			audiences:         audiences,
+			expirationSeconds: 3600,
+			boundObjectRef:    &authenticationv1.BoundObjectReference{Kind: "Secret", APIVersion: "v1", Name: "my-secret"},
+		},
+		{
+			name:              "separator in cluster name",
+			identity:          ServiceAccountIdentity{Cluster: "cluster-a/uid-1", Namespace: "default", Name: "my-sa", UID: ""},
+			namespace:         "default",
+			serviceAccount:    "my-sa",
+			audiences:         audiences,
+			expirationSeconds: 3600,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exchangeCacheKey(&tt.identity, tt.namespace, tt.serviceAccount, tt.audiences, tt.expirationSeconds, tt.boundObjectRef); got == key {
+				t.Errorf("Expected distinct cache key, got %q for both", got)
+			}
+		})
+	}
+
+	// Tokens bound to objects with the same name but another UID aren't shared
+	bound := &authenticationv1.BoundObjectReference{Kind: "Secret", APIVersion: "v1", Name: "my-secret", UID: "secret-uid-1"}
+	recreated := &authenticationv1.BoundObjectReference{Kind: "Secret", APIVersion: "v1", Name: "my-secret", UID: "secret-uid-2"}
+	if exchangeCacheKey(identity, "default", "my-sa", audiences, 3600, bound) == exchangeCacheKey(identity, "default", "my-sa", audiences, 3600, recreated) {
+		t.Error("Expected distinct cache keys for bound objects with different UIDs")
+	}
+}
+
+func TestExchanger_Evict(t *testing.T) {
+	exchanger := NewExchanger(nil, ExchangeConfig{})
+	expiresAt := time.Now().Add(time.Hour)
+
+	evicted := &ServiceAccountIdentity{Cluster: "cluster-a", Namespace: "default", Name: "my-sa", UID: "uid-1"}
+	otherCluster := &ServiceAccountIdentity{Cluster: "cluster-b", Namespace: "default", Name: "my-sa", UID: "uid-1"}
+
+	keyFor := func(identity *ServiceAccountIdentity) string {
+		return exchangeCacheKey(identity, identity.Namespace, identity.Name, exchanger.config.Audiences, *exchanger.config.ExpirationSeconds, nil)
+	}
+	exchanger.cache.Set(keyFor(evicted), "token-1", expiresAt)
+	exchanger.cache.Set(keyFor(otherCluster), "token-2", expiresAt)
+
+	exchanger.Evict("cluster-a", "uid-1")
+
+	if _, found := exchanger.cache.Get(keyFor(evicted)); found {
+		t.Error("Expected token of evicted service account to be removed")
+	}
+	if _, found := exchanger.cache.Get(keyFor(otherCluster)); !found {
+		t.Error("Expected token of the same UID in another cluster to be kept")
+	}
+}
+
+func TestClusterConfig_AllowsManagementNamespace(t *testing.T) {
+	tests := []struct {
+		name       string
+		namespaces []string
+		namespace  string
+		expected   bool
+	}{
+		{name: "default allows tenant namespace", namespace: "tenant-a", expected: true},
+		{name: "default denies kube-system", namespace: "kube-system", expected: false},
+		{name: "glob match", namespaces: []string{"tenant-a-*"}, namespace: "tenant-a-web", expected: true},
+		{name: "glob mismatch", namespaces: []string{"tenant-a-*"}, namespace: "tenant-b-web", expected: false},
+		{name: "wildcard excludes system namespaces", namespaces: []string{"*"}, namespace: "kube-public", expected: false},
+		{name: "system namespace listed by name", namespaces: []string{"kube-system"}, namespace: "kube-system", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &config.ClusterConfig{Name: "tenant-a", ManagementNamespaces: tt.namespaces}
+			if got := cluster.AllowsManagementNamespace(tt.namespace); got != tt.expected {
+				t.Errorf("Expected %v for namespace %s, got %v", tt.expected, tt.namespace, got)
+			}
+		})
+	}
+}
+
+func TestExchanger_ManagementNamespaces(t *testing.T) {
+	ctx := context.Background()
+
+	managementClient := fake.NewSimpleClientset(
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "deployer", Namespace: "tenant-a"}},
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "deployer", Namespace: "tenant-b"}},
+	)
+	createTokenCalls := 0
+	managementClient.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+		createTokenCalls++
+		tokenReq := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenRequest)
+		tokenReq.Status = authenticationv1.TokenRequestStatus{
+			Token:               "management-token",
+			ExpirationTimestamp: metav1.Time{Time: time.Now().Add(time.Hour)},
+		}
+		return true, tokenReq, nil
+	})
+
+	exchanger := NewExchanger(managementClient, ExchangeConfig{
+		Clusters: &config.ClustersConfig{
+			Clusters: []config.ClusterConfig{
+				{Name: "tenant-a", ManagementNamespaces: []string{"tenant-a"}},
+			},
+		},
+	})
+
+	tests := []struct {
+		name        string
+		identity    *ServiceAccountIdentity
+		expectError bool
+	}{
+		{
+			name:     "allowed namespace",
+			identity: &ServiceAccountIdentity{Cluster: "tenant-a", Namespace: "tenant-a", Name: "deployer", UID: "uid-1"},
+		},
+		{
+			name:        "other tenant",
+			identity:    &ServiceAccountIdentity{Cluster: "tenant-a", Namespace: "tenant-b", Name: "deployer", UID: "uid-2"},
+			expectError: true,
+		},
+		{
+			name:        "unknown cluster",
+			identity:    &ServiceAccountIdentity{Cluster: "tenant-c", Namespace: "tenant-a", Name: "deployer", UID: "uid-3"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			createTokenCalls = 0
+			_, err := exchanger.Exchange(ctx, tt.identity)
+			if tt.expectError {
+				if !errors.Is(err, ErrExchangeDenied) {
+					t.Fatalf("Expected ErrExchangeDenied, got %v", err)
+				}
+				if createTokenCalls != 0 {
+					t.Errorf("Expected no CreateToken calls, got %d", createTokenCalls)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestExchanger_CachedExpirationTime(t *testing.T) {
+	ctx := context.Background()
+
+	// The token-ttl annotation makes the issued token outlive the configured
+	// expiration
+	managementClient := fake.NewSimpleClientset(&corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "deployer",
+			Namespace:   "default",
+			Annotations: map[string]string{TokenTTLAnnotation: "2h"},
+		},
+	})
+	createTokenCalls := 0
+	managementClient.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+		createTokenCalls++
+		tokenReq := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenRequest)
+		tokenReq.Status = authenticationv1.TokenRequestStatus{
+			Token:               "management-token",
+			ExpirationTimestamp: metav1.Time{Time: time.Now().Add(time.Duration(*tokenReq.Spec.ExpirationSeconds) * time.Second)},
+		}
+		return true, tokenReq, nil
+	})
+
+	exchanger := NewExchanger(managementClient, ExchangeConfig{})
+	identity := &ServiceAccountIdentity{Cluster: "cluster", Namespace: "default", Name: "deployer", UID: "uid-1"}
+
+	issued, err := exchanger.ExchangeWithMetadata(ctx, identity)
+	if err != nil {
+		t.Fatalf("Exchange failed: %v", err)
+	}
+	cached, err := exchanger.ExchangeWithMetadata(ctx, identity)
+	if err != nil {
+		t.Fatalf("Cached exchange failed: %v", err)
+	}
+
+	if createTokenCalls != 1 {
+		t.Errorf("Expected 1 CreateToken call, got %d", createTokenCalls)
+	}
+	if !cached.ExpirationTime.Equal(issued.ExpirationTime) {
+		t.Errorf("Expected cached token to expire at %v, got %v", issued.ExpirationTime, cached.ExpirationTime)
+	}
+}
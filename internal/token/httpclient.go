@@ -0,0 +1,192 @@
+package token
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/holos-run/tokensmith/internal/config"
+)
+
+// fetchTimeout is the overall timeout for a single JWKS or discovery fetch.
+const fetchTimeout = 10 * time.Second
+
+// fetcher fetches JSON documents for a single cluster.
+type fetcher struct {
+	client       *http.Client
+	maxBodyBytes int64
+}
+
+// fetcherFor returns the fetcher for a cluster. Clusters with workload access
+// fetch through the API server transport; other clusters use an HTTP client
+// built from the cluster's TLS, proxy and redirect settings, created once and
+// reused.
+func (v *JWKSValidator) fetcherFor(cluster *config.ClusterConfig) (*fetcher, error) {
+	if t := v.transportFor(cluster.Name); t != nil {
+		return &fetcher{
+			client:       t.client,
+			maxBodyBytes: cluster.GetMaxResponseBytes(),
+		}, nil
+	}
+
+	v.mu.RLock()
+	f, ok := v.fetchers[cluster.Name]
+	v.mu.RUnlock()
+	if ok {
+		return f, nil
+	}
+
+	client, err := newHTTPClient(cluster)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client for cluster %s: %w", cluster.Name, err)
+	}
+	f = &fetcher{
+		client:       client,
+		maxBodyBytes: cluster.GetMaxResponseBytes(),
+	}
+
+	v.mu.Lock()
+	v.fetchers[cluster.Name] = f
+	v.mu.Unlock()
+
+	return f, nil
+}
+
+// newHTTPClient creates an HTTP client for fetching a cluster's JWKS and
+// discovery documents.
+func newHTTPClient(cluster *config.ClusterConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	transport.Proxy = http.ProxyFromEnvironment
+	if cluster.ProxyURL != "" {
+		proxyURL, err := url.Parse(cluster.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig, err := newTLSConfig(cluster.TLS)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{
+		Transport:     transport,
+		Timeout:       fetchTimeout,
+		CheckRedirect: redirectPolicy(cluster.MaxRedirects),
+	}, nil
+}
+
+// newTLSConfig creates a TLS configuration from the cluster TLS settings.
+func newTLSConfig(cfg *config.TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if cfg == nil {
+		return tlsConfig, nil
+	}
+
+	tlsConfig.ServerName = cfg.ServerName
+
+	if cfg.CAFile != "" {
+		pemData, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pemData) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// redirectPolicy returns a CheckRedirect function that follows at most
+// maxRedirects redirects and never downgrades from HTTPS to HTTP.
+func redirectPolicy(maxRedirects int) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) > maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		if via[0].URL.Scheme == "https" && req.URL.Scheme != "https" {
+			return errors.New("refusing redirect from https to " + req.URL.Scheme)
+		}
+		return nil
+	}
+}
+
+// fetchJSON fetches a JSON document from a URI and decodes it into out.
+// It returns the response headers.
+func (f *fetcher) fetchJSON(ctx context.Context, uri string, out interface{}) (http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json, application/jwk-set+json")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	if err := checkJSONContentType(resp.Header.Get("Content-Type")); err != nil {
+		return nil, err
+	}
+
+	// Read at most one byte past the limit to detect oversized responses
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBodyBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if int64(len(body)) > f.maxBodyBytes {
+		return nil, fmt.Errorf("response body exceeds %d bytes", f.maxBodyBytes)
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return resp.Header, nil
+}
+
+// checkJSONContentType verifies that a response content type is JSON, such
+// as application/json or application/jwk-set+json.
+func checkJSONContentType(contentType string) error {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("invalid content type %q", contentType)
+	}
+
+	if mediaType != "application/json" && !(strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+json")) {
+		return fmt.Errorf("unexpected content type %q", mediaType)
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package token
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/holos-run/tokensmith/internal/config"
+)
+
+func TestFetcher_FetchJSON(t *testing.T) {
+	ctx := context.Background()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{"issuer": "https://issuer.example.com"})
+	})
+	mux.HandleFunc("/jwk-set", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/jwk-set+json; charset=utf-8")
+		_, _ = w.Write([]byte(`{"keys":[]}`))
+	})
+	mux.HandleFunc("/html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html></html>`))
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"padding":"` + strings.Repeat("a", 2048) + `"}`))
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		cluster config.ClusterConfig
+		path    string
+		wantErr bool
+	}{
+		{
+			name: "json response",
+			path: "/ok",
+		},
+		{
+			name: "jwk set media type",
+			path: "/jwk-set",
+		},
+		{
+			name:    "reject non-json content type",
+			path:    "/html",
+			wantErr: true,
+		},
+		{
+			name:    "reject oversized response",
+			cluster: config.ClusterConfig{MaxResponseBytes: 1024},
+			path:    "/large",
+			wantErr: true,
+		},
+		{
+			name:    "reject redirect by default",
+			path:    "/redirect",
+			wantErr: true,
+		},
+		{
+			name:    "follow redirect when allowed",
+			cluster: config.ClusterConfig{MaxRedirects: 1},
+			path:    "/redirect",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := newHTTPClient(&tt.cluster)
+			if err != nil {
+				t.Fatalf("Failed to create HTTP client: %v", err)
+			}
+			f := &fetcher{
+				client:       client,
+				maxBodyBytes: tt.cluster.GetMaxResponseBytes(),
+			}
+
+			var out map[string]interface{}
+			_, err = f.fetchJSON(ctx, server.URL+tt.path, &out)
+			if tt.wantErr && err == nil {
+				t.Fatal("Expected error but got none")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewHTTPClient_Proxy(t *testing.T) {
+	// The proxy answers every request itself
+	var proxied bool
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = true
+		writeJSON(w, map[string]string{})
+	}))
+	defer proxy.Close()
+
+	client, err := newHTTPClient(&config.ClusterConfig{ProxyURL: proxy.URL})
+	if err != nil {
+		t.Fatalf("Failed to create HTTP client: %v", err)
+	}
+	f := &fetcher{client: client, maxBodyBytes: config.DefaultMaxResponseBytes}
+
+	var out map[string]interface{}
+	if _, err := f.fetchJSON(context.Background(), "http://issuer.invalid/keys", &out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !proxied {
+		t.Error("Expected request to go through the proxy")
+	}
+}
+
+func TestNewHTTPClient_InvalidTLS(t *testing.T) {
+	_, err := newHTTPClient(&config.ClusterConfig{
+		TLS: &config.TLSConfig{CAFile: "/nonexistent/ca.pem"},
+	})
+	if err == nil {
+		t.Fatal("Expected error for missing CA file")
+	}
+}
@@ -0,0 +1,92 @@
+package token
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/holos-run/tokensmith/internal/config"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// mapIdentity returns the management cluster service account an identity
+// exchanges for using the first identity mapping that matches it. Identities
+// matching no mapping exchange for the service account with the same
+// namespace and name.
+func mapIdentity(mappings []config.IdentityMapping, identity *ServiceAccountIdentity) (namespace, name string, err error) {
+	for i := range mappings {
+		mapping := &mappings[i]
+
+		matched, err := matchIdentity(mapping, identity)
+		if err != nil {
+			return "", "", fmt.Errorf("identity mapping %d: %w", i, err)
+		}
+		if !matched {
+			continue
+		}
+
+		if mapping.Deny {
+			return "", "", fmt.Errorf("%w: identity mapping %d denies %s/%s from cluster %s",
+				ErrExchangeDenied, i, identity.Namespace, identity.Name, identity.Cluster)
+		}
+
+		data := config.IdentityTemplateData{
+			Cluster:   identity.Cluster,
+			Namespace: identity.Namespace,
+			Name:      identity.Name,
+		}
+
+		namespace, name = identity.Namespace, identity.Name
+		if mapping.TargetNamespace != "" {
+			namespace, err = renderMappingTemplate(mapping.TargetNamespaceTemplate, data)
+			if err != nil {
+				return "", "", fmt.Errorf("identity mapping %d: target_namespace: %w", i, err)
+			}
+			if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+				return "", "", fmt.Errorf("identity mapping %d: invalid namespace %q: %s", i, namespace, strings.Join(errs, ", "))
+			}
+		}
+		if mapping.TargetName != "" {
+			name, err = renderMappingTemplate(mapping.TargetNameTemplate, data)
+			if err != nil {
+				return "", "", fmt.Errorf("identity mapping %d: target_name: %w", i, err)
+			}
+			if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+				return "", "", fmt.Errorf("identity mapping %d: invalid name %q: %s", i, name, strings.Join(errs, ", "))
+			}
+		}
+		return namespace, name, nil
+	}
+
+	return identity.Namespace, identity.Name, nil
+}
+
+// matchIdentity reports whether all selectors of a mapping match an identity.
+func matchIdentity(mapping *config.IdentityMapping, identity *ServiceAccountIdentity) (bool, error) {
+	if len(mapping.Clusters) > 0 && !slices.Contains(mapping.Clusters, identity.Cluster) {
+		return false, nil
+	}
+
+	if mapping.Namespace != "" {
+		matched, err := path.Match(mapping.Namespace, identity.Namespace)
+		if err != nil {
+			return false, fmt.Errorf("namespace: %w", err)
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+
+	if mapping.Name != "" {
+		re, err := mapping.NameRegexp()
+		if err != nil {
+			return false, fmt.Errorf("name: %w", err)
+		}
+		if !re.MatchString(identity.Name) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,316 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/holos-run/tokensmith/internal/config"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// tenantMappings deny kube-system, map the deployers of every tenant cluster
+// to a single service account and map all other identities into the tenant
+// namespace.
+var tenantMappings = []config.IdentityMapping{
+	{
+		Namespace: "kube-*",
+		Deny:      true,
+	},
+	{
+		Clusters:        []string{"tenant-a-prod", "tenant-a-dev"},
+		Name:            "deploy(er)?",
+		TargetNamespace: "tenant-a",
+		TargetName:      "deployer",
+	},
+	{
+		Clusters:        []string{"tenant-a-prod", "tenant-a-dev"},
+		TargetNamespace: "tenant-a",
+		TargetName:      "{{ .Cluster }}-{{ .Namespace }}",
+	},
+}
+
+func TestMapIdentity(t *testing.T) {
+	tests := []struct {
+		name         string
+		identity     ServiceAccountIdentity
+		mappings     []config.IdentityMapping
+		expectDenied bool
+		expectError  bool
+		expectedNS   string
+		expectedName string
+	}{
+		{
+			name:         "no mappings",
+			identity:     ServiceAccountIdentity{Cluster: "tenant-a-prod", Namespace: "web", Name: "api"},
+			expectedNS:   "web",
+			expectedName: "api",
+		},
+		{
+			name:         "many to one",
+			identity:     ServiceAccountIdentity{Cluster: "tenant-a-dev", Namespace: "ci", Name: "deploy"},
+			mappings:     tenantMappings,
+			expectedNS:   "tenant-a",
+			expectedName: "deployer",
+		},
+		{
+			name:         "template",
+			identity:     ServiceAccountIdentity{Cluster: "tenant-a-prod", Namespace: "web", Name: "api"},
+			mappings:     tenantMappings,
+			expectedNS:   "tenant-a",
+			expectedName: "tenant-a-prod-web",
+		},
+		{
+			name:         "name must fully match",
+			identity:     ServiceAccountIdentity{Cluster: "tenant-a-prod", Namespace: "web", Name: "deployer-2"},
+			mappings:     tenantMappings,
+			expectedNS:   "tenant-a",
+			expectedName: "tenant-a-prod-web",
+		},
+		{
+			name:         "deny wins by order",
+			identity:     ServiceAccountIdentity{Cluster: "tenant-a-prod", Namespace: "kube-system", Name: "deploy"},
+			mappings:     tenantMappings,
+			expectDenied: true,
+		},
+		{
+			name:         "other cluster keeps its identity",
+			identity:     ServiceAccountIdentity{Cluster: "tenant-b", Namespace: "web", Name: "api"},
+			mappings:     tenantMappings,
+			expectedNS:   "web",
+			expectedName: "api",
+		},
+		{
+			name:     "rendered name is not a valid service account name",
+			identity: ServiceAccountIdentity{Cluster: "Tenant", Namespace: "web", Name: "api"},
+			mappings: []config.IdentityMapping{
+				{TargetName: "{{ .Cluster }}_{{ .Name }}"},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespace, name, err := mapIdentity(tt.mappings, &tt.identity)
+
+			if tt.expectDenied {
+				if !errors.Is(err, ErrExchangeDenied) {
+					t.Fatalf("Expected ErrExchangeDenied, got %v", err)
+				}
+				return
+			}
+			if tt.expectError {
+				if err == nil {
+					t.Fatalf("Expected error, got %s/%s", namespace, name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if namespace != tt.expectedNS || name != tt.expectedName {
+				t.Errorf("Expected %s/%s, got %s/%s", tt.expectedNS, tt.expectedName, namespace, name)
+			}
+		})
+	}
+}
+
+func TestExchanger_IdentityMapping(t *testing.T) {
+	ctx := context.Background()
+
+	managementClient := fake.NewSimpleClientset(&corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "deployer", Namespace: "tenant-a"},
+	})
+
+	var requested string
+	managementClient.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+		createAction := action.(k8stesting.CreateActionImpl)
+		requested = createAction.GetNamespace() + "/" + createAction.Name
+		tokenReq := createAction.GetObject().(*authenticationv1.TokenRequest)
+		tokenReq.Status = authenticationv1.TokenRequestStatus{
+			Token:               "management-token",
+			ExpirationTimestamp: metav1.Time{Time: time.Now().Add(time.Hour)},
+		}
+		return true, tokenReq, nil
+	})
+
+	exchanger := NewExchanger(managementClient, ExchangeConfig{IdentityMappings: tenantMappings})
+
+	identity := &ServiceAccountIdentity{Cluster: "tenant-a-prod", Namespace: "ci", Name: "deployer", UID: "uid-1"}
+	metadata, err := exchanger.ExchangeWithMetadata(ctx, identity)
+	if err != nil {
+		t.Fatalf("Exchange failed: %v", err)
+	}
+	if metadata.Namespace != "tenant-a" || metadata.ServiceAccount != "deployer" {
+		t.Errorf("Expected tenant-a/deployer, got %s/%s", metadata.Namespace, metadata.ServiceAccount)
+	}
+	if requested != "tenant-a/deployer" {
+		t.Errorf("Expected TokenRequest for tenant-a/deployer, got %s", requested)
+	}
+
+	denied := &ServiceAccountIdentity{Cluster: "tenant-a-prod", Namespace: "kube-system", Name: "deployer", UID: "uid-2"}
+	if _, err := exchanger.Exchange(ctx, denied); !errors.Is(err, ErrExchangeDenied) {
+		t.Errorf("Expected ErrExchangeDenied, got %v", err)
+	}
+}
+
+func TestExchanger_IdentityMappingCache(t *testing.T) {
+	ctx := context.Background()
+
+	managementClient := fake.NewSimpleClientset(&corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "deployer", Namespace: "tenant-a"},
+	})
+
+	createTokenCalls := 0
+	managementClient.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+		createTokenCalls++
+		tokenReq := action.(k8stesting.CreateActionImpl).GetObject().(*authenticationv1.TokenRequest)
+		tokenReq.Status = authenticationv1.TokenRequestStatus{
+			Token:               fmt.Sprintf("management-token-%d", createTokenCalls),
+			ExpirationTimestamp: metav1.Time{Time: time.Now().Add(time.Hour)},
+		}
+		return true, tokenReq, nil
+	})
+
+	exchanger := NewExchanger(managementClient, ExchangeConfig{IdentityMappings: tenantMappings})
+
+	// Both identities map to tenant-a/deployer
+	prod := &ServiceAccountIdentity{Cluster: "tenant-a-prod", Namespace: "ci", Name: "deployer", UID: "uid-1"}
+	dev := &ServiceAccountIdentity{Cluster: "tenant-a-dev", Namespace: "ci", Name: "deploy", UID: "uid-2"}
+
+	exchange := func(identity *ServiceAccountIdentity) string {
+		t.Helper()
+		metadata, err := exchanger.ExchangeWithMetadata(ctx, identity)
+		if err != nil {
+			t.Fatalf("Exchange failed: %v", err)
+		}
+		if metadata.Namespace != "tenant-a" || metadata.ServiceAccount != "deployer" {
+			t.Errorf("Expected tenant-a/deployer, got %s/%s", metadata.Namespace, metadata.ServiceAccount)
+		}
+		return metadata.Token
+	}
+
+	prodToken := exchange(prod)
+	devToken := exchange(dev)
+	if prodToken == devToken {
+		t.Errorf("Expected identities mapped to the same service account not to share a cached token, got %q for both", prodToken)
+	}
+	if got := exchange(prod); got != prodToken {
+		t.Errorf("Expected cached token %q, got %q", prodToken, got)
+	}
+	if createTokenCalls != 2 {
+		t.Errorf("Expected 2 CreateToken calls, got %d", createTokenCalls)
+	}
+
+	// Evicting one identity keeps the token cached for the other
+	exchanger.Evict("tenant-a-prod", "uid-1")
+	if got := exchange(dev); got != devToken {
+		t.Errorf("Expected cached token %q, got %q", devToken, got)
+	}
+	if createTokenCalls != 2 {
+		t.Errorf("Expected eviction of one identity to keep the other cached, got %d CreateToken calls", createTokenCalls)
+	}
+}
+
+func TestClustersConfig_ValidateIdentityMappings(t *testing.T) {
+	tests := []struct {
+		name        string
+		mapping     config.IdentityMapping
+		expectError bool
+	}{
+		{
+			name:    "valid",
+			mapping: config.IdentityMapping{Clusters: []string{"cluster"}, Namespace: "team-*", TargetName: "{{ .Cluster }}-{{ .Namespace }}"},
+		},
+		{
+			name:        "unknown cluster",
+			mapping:     config.IdentityMapping{Clusters: []string{"other"}, TargetNamespace: "tenant"},
+			expectError: true,
+		},
+		{
+			name:        "invalid glob",
+			mapping:     config.IdentityMapping{Namespace: "team-[", TargetNamespace: "tenant"},
+			expectError: true,
+		},
+		{
+			name:        "invalid regular expression",
+			mapping:     config.IdentityMapping{Name: "(deploy", TargetNamespace: "tenant"},
+			expectError: true,
+		},
+		{
+			name:        "template references unknown field",
+			mapping:     config.IdentityMapping{TargetName: "{{ .Pod }}"},
+			expectError: true,
+		},
+		{
+			name:        "deny with target",
+			mapping:     config.IdentityMapping{Deny: true, TargetNamespace: "tenant"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.ClustersConfig{
+				Clusters: []config.ClusterConfig{
+					{
+						Name:      "cluster",
+						Issuer:    "https://cluster.example.com",
+						Audiences: []string{"tokensmith"},
+						JWKSURI:   "https://cluster.example.com/openid/v1/jwks",
+					},
+				},
+				IdentityMappings: []config.IdentityMapping{tt.mapping},
+			}
+			err := cfg.Validate()
+			if tt.expectError && err == nil {
+				t.Error("Expected validation error")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestIdentityMapping_CompiledByValidate(t *testing.T) {
+	mapping := config.IdentityMapping{
+		Name:            "deploy.*",
+		TargetNamespace: "tenant-{{ .Cluster }}",
+		TargetName:      "{{ .Namespace }}-{{ .Name }}",
+	}
+	if err := mapping.Validate(); err != nil {
+		t.Fatalf("Invalid identity mapping: %v", err)
+	}
+
+	// Validate compiles the mapping once so exchanges don't recompile it
+	re1, _ := mapping.NameRegexp()
+	re2, _ := mapping.NameRegexp()
+	if re1 == nil || re1 != re2 {
+		t.Error("Expected name regexp to be compiled once by Validate")
+	}
+	ns1, _ := mapping.TargetNamespaceTemplate()
+	ns2, _ := mapping.TargetNamespaceTemplate()
+	if ns1 == nil || ns1 != ns2 {
+		t.Error("Expected target namespace template to be compiled once by Validate")
+	}
+	name1, _ := mapping.TargetNameTemplate()
+	name2, _ := mapping.TargetNameTemplate()
+	if name1 == nil || name1 != name2 {
+		t.Error("Expected target name template to be compiled once by Validate")
+	}
+}
@@ -2,54 +2,145 @@ package token
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-jose/go-jose/v4"
 	"github.com/go-jose/go-jose/v4/jwt"
 	"github.com/holos-run/tokensmith/internal/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 )
 
+const (
+	// jwksCacheTTL is how long fetched JWKS and discovery documents are cached
+	// when the response doesn't specify a Cache-Control max-age.
+	jwksCacheTTL = time.Hour
+
+	// minJWKSCacheTTL and maxJWKSCacheTTL bound the cache lifetime derived
+	// from a Cache-Control max-age.
+	minJWKSCacheTTL = time.Minute
+	maxJWKSCacheTTL = 24 * time.Hour
+
+	// defaultMinRefetchInterval is the minimum time between forced JWKS
+	// refetches for an issuer when tokens reference unknown key IDs.
+	defaultMinRefetchInterval = 30 * time.Second
+)
+
+// ErrKeysUnavailable is returned when a token can't be verified because the
+// signing keys of its cluster can't be obtained.
+var ErrKeysUnavailable = errors.New("signing keys unavailable")
+
 // JWKSValidator validates JWT tokens using JWKS.
 type JWKSValidator struct {
 	config *config.ClustersConfig
-	client *http.Client
-	cache  map[string]*cachedJWKS
 	mu     sync.RWMutex
+
+	// cache holds fetched JWKS and discovery documents indexed by URI.
+	cache map[string]*cachedDocument
+
+	// inflight holds outstanding JWKS and discovery fetches indexed by URI.
+	inflight map[string]*inflightFetch
+
+	// fetchFailures tracks consecutive failed fetches indexed by URI, so
+	// background refreshes of stale documents back off.
+	fetchFailures map[string]*fetchFailure
+
+	// lastRefetch records the last forced JWKS refetch per cluster name.
+	lastRefetch        map[string]time.Time
+	minRefetchInterval time.Duration
+
+	// fileCache holds key sets loaded from JWKS files indexed by cluster name.
+	fileCache map[string]*fileJWKS
+
+	// fetchers holds HTTP fetchers indexed by cluster name.
+	fetchers map[string]*fetcher
+
+	// workloadClients holds workload cluster clients indexed by cluster name.
+	workloadClients map[string]kubernetes.Interface
+
+	// workloadTransports holds authenticated API server transports indexed
+	// by cluster name.
+	workloadTransports map[string]*workloadTransport
+
+	// replays tracks the sources token IDs were first seen from for clusters
+	// with replay protection.
+	replays *replayTracker
+
+	// serviceAccounts holds service account informers indexed by cluster name.
+	serviceAccounts map[string]*serviceAccountWatcher
+
+	// serviceAccountHandlers are called when a watched service account is
+	// deleted or recreated.
+	serviceAccountHandlers []func(cluster, uid string)
 }
 
-// cachedJWKS holds a cached JWKS with its fetch time.
-type cachedJWKS struct {
+// inflightFetch is a JWKS or discovery fetch shared by concurrent callers.
+// doc and err are set before done is closed.
+type inflightFetch struct {
+	done chan struct{}
+	doc  *cachedDocument
+	err  error
+}
+
+// cachedDocument holds a cached JWKS or discovery document with its fetch
+// and expiration times. Exactly one of jwks and discovery is set.
+type cachedDocument struct {
 	jwks      *jose.JSONWebKeySet
+	discovery *discoveryDocument
 	fetchedAt time.Time
+	expiresAt time.Time
 }
 
+// fetchFailure records consecutive failed fetches of a URI, the last error
+// and when the next background refresh or inline fetch may be attempted.
+type fetchFailure struct {
+	count   int
+	err     error
+	retryAt time.Time
+}
+
+// documentFetcher fetches a JWKS or discovery document and returns it with
+// its cache lifetime.
+type documentFetcher func(ctx context.Context) (*cachedDocument, time.Duration, error)
+
 // NewJWKSValidator creates a new JWKS validator.
 func NewJWKSValidator(cfg *config.ClustersConfig) *JWKSValidator {
 	return &JWKSValidator{
-		config: cfg,
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
-		cache: make(map[string]*cachedJWKS),
+		config:          cfg,
+		fileCache:       make(map[string]*fileJWKS),
+		fetchers:        make(map[string]*fetcher),
+		cache:           make(map[string]*cachedDocument),
+		workloadClients: make(map[string]kubernetes.Interface),
+
+		workloadTransports: make(map[string]*workloadTransport),
+		inflight:           make(map[string]*inflightFetch),
+		fetchFailures:      make(map[string]*fetchFailure),
+		lastRefetch:        make(map[string]time.Time),
+		minRefetchInterval: defaultMinRefetchInterval,
+		replays:            newReplayTracker(),
+		serviceAccounts:    make(map[string]*serviceAccountWatcher),
 	}
 }
 
+// SetWorkloadClient sets the client used to query the named workload cluster.
+// It must be called before Validate for clusters that require workload access,
+// such as those with VerifyBoundPod enabled.
+func (v *JWKSValidator) SetWorkloadClient(cluster string, client kubernetes.Interface) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.workloadClients[cluster] = client
+}
+
 // Validate validates a JWT token and returns the service account identity.
 func (v *JWKSValidator) Validate(ctx context.Context, tokenString string) (*ServiceAccountIdentity, error) {
 	// Parse the token without verification first to extract claims
-	tok, err := jwt.ParseSigned(tokenString, []jose.SignatureAlgorithm{
-		jose.RS256,
-		jose.RS384,
-		jose.RS512,
-		jose.ES256,
-		jose.ES384,
-		jose.ES512,
-	})
+	tok, err := jwt.ParseSigned(tokenString, config.SupportedSigningAlgorithms)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
 	}
@@ -61,40 +152,201 @@ func (v *JWKSValidator) Validate(ctx context.Context, tokenString string) (*Serv
 		return nil, fmt.Errorf("failed to extract claims: %w", err)
 	}
 
-	// Find the cluster configuration by issuer
-	clusterConfig := v.config.FindByIssuer(claims.Issuer)
-	if clusterConfig == nil {
-		return nil, fmt.Errorf("unknown issuer: %s", claims.Issuer)
+	// Find the cluster configuration by issuer, or by signing key for legacy
+	// tokens which share an issuer across clusters
+	kind := classifyToken(&claims, k8sClaims)
+	var clusterConfig *config.ClusterConfig
+	if claims.Issuer == legacyIssuer {
+		clusterConfig, err = v.findLegacyCluster(ctx, tok.Headers[0].KeyID)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		clusterConfig, err = findCluster(v.config, &claims)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if kind == LegacyToken && clusterConfig.LegacyTokens == nil {
+		return nil, ErrLegacyToken
+	}
+
+	// Restrict signing algorithms to those permitted for the cluster
+	algs, err := v.signingAlgorithms(ctx, clusterConfig)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrKeysUnavailable, err)
+	}
+	if err := checkSigningAlgorithm(tok, algs); err != nil {
+		return nil, err
 	}
 
 	// Get the JWKS for this cluster
 	jwks, err := v.getJWKS(ctx, clusterConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get JWKS: %w", err)
+		return nil, fmt.Errorf("%w: failed to get JWKS: %w", ErrKeysUnavailable, err)
 	}
 
-	// Verify the token signature and validate claims
-	if err := tok.Claims(jwks, &claims, &k8sClaims); err != nil {
+	// Refetch the JWKS if the token references an unknown key, e.g. after
+	// the workload cluster rotated its signing key
+	if kid := tok.Headers[0].KeyID; kid != "" && len(jwks.Key(kid)) == 0 && clusterConfig.IsRemote() {
+		jwks, err = v.refetchJWKS(ctx, clusterConfig)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to refetch JWKS for unknown key ID %q: %w", ErrKeysUnavailable, kid, err)
+		}
+	}
+
+	// Verify the token signature with a key matching the header algorithm
+	key, err := verificationKey(jwks, tok.Headers[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify token: %w", err)
+	}
+	if err := tok.Claims(key, &claims, &k8sClaims); err != nil {
 		return nil, fmt.Errorf("failed to verify token: %w", err)
 	}
 
-	// Validate standard JWT claims
-	expected := jwt.Expected{
-		Time: time.Now(),
+	// Validate standard JWT claims, including the audience. Legacy tokens
+	// carry no audience or expiry; their age is checked against the Secret.
+	now := time.Now()
+	if kind == LegacyToken {
+		err = validateClaims(&claims, nil, nil, now)
+	} else {
+		err = validateClaims(&claims, clusterConfig.Audiences, clusterConfig.ClaimValidation, now)
 	}
-	if err := claims.Validate(expected); err != nil {
+	if err != nil {
 		return nil, fmt.Errorf("invalid claims: %w", err)
 	}
 
-	// Extract Kubernetes service account identity from claims
-	identity, err := extractServiceAccountIdentity(&claims, k8sClaims)
-	if err != nil {
-		return nil, fmt.Errorf("failed to extract service account identity: %w", err)
+	// Reject tokens minted by exchanges to prevent exchange loops
+	for _, aud := range v.config.GetExchangedAudiences() {
+		if claims.Audience.Contains(aud) {
+			return nil, fmt.Errorf("token carries management cluster audience %q", aud)
+		}
+	}
+
+	var identity *ServiceAccountIdentity
+	switch clusterConfig.GetType() {
+	case config.ClusterTypeOIDC:
+		// Map the claims of other OIDC issuers to a service account
+		identity, err = mapOIDCIdentity(&claims, k8sClaims, clusterConfig.Mappings)
+		if err != nil {
+			return nil, fmt.Errorf("failed to map identity: %w", err)
+		}
+		kind = OIDCToken
+	case config.ClusterTypeSPIFFE:
+		// JWT-SVIDs must expire and identify the workload by SPIFFE ID
+		if claims.Expiry == nil {
+			return nil, fmt.Errorf("invalid claims: JWT-SVID has no exp claim")
+		}
+		identity, err = extractSPIFFEIdentity(&claims, clusterConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract SPIFFE identity: %w", err)
+		}
+		kind = SPIFFEToken
+	default:
+		// Extract Kubernetes service account identity from claims
+		identity, err = extractServiceAccountIdentity(&claims, k8sClaims, clusterConfig.ClaimPaths)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract service account identity: %w", err)
+		}
+
+		// Require the subject to name the same service account as the kubernetes claims
+		if profile := clusterConfig.ClaimValidation; profile != nil && profile.RequireSubjectMatch && claims.Subject != identity.Username {
+			return nil, fmt.Errorf("invalid claims: subject %q does not match service account %s", claims.Subject, identity.Username)
+		}
+	}
+	identity.Cluster = clusterConfig.Name
+	identity.Kind = kind
+	identity.TokenID = claims.ID
+
+	if kind == LegacyToken {
+		if err := v.checkLegacyToken(ctx, clusterConfig, identity, now); err != nil {
+			return nil, err
+		}
+	}
+
+	if clusterConfig.VerifyBoundPod {
+		if err := v.verifyBoundPod(ctx, clusterConfig.Name, identity); err != nil {
+			return nil, err
+		}
+	}
+
+	if clusterConfig.VerifyServiceAccount {
+		if err := v.verifyServiceAccount(clusterConfig.Name, identity); err != nil {
+			return nil, err
+		}
+	}
+
+	if clusterConfig.ReplayProtection != nil {
+		if err := v.checkReplay(ctx, clusterConfig, &claims, identity, now); err != nil {
+			return nil, err
+		}
 	}
 
 	return identity, nil
 }
 
+// checkReplay records where a token was presented from and detects when it
+// is presented from a different source than it was first seen from. Replayed
+// tokens are rejected or flagged on the identity depending on the cluster's
+// replay action. Tokens without a jti or exp can't be tracked.
+func (v *JWKSValidator) checkReplay(ctx context.Context, cluster *config.ClusterConfig, claims *jwt.Claims, identity *ServiceAccountIdentity, now time.Time) error {
+	if claims.ID == "" || claims.Expiry == nil {
+		return nil
+	}
+
+	source, _ := SourceFromContext(ctx)
+	first, replayed := v.replays.observe(cluster.Name+"/"+claims.ID, source, claims.Expiry.Time(), now)
+	if !replayed {
+		return nil
+	}
+
+	if cluster.ReplayProtection.GetAction() == config.ReplayActionDeny {
+		return fmt.Errorf("%w: jti %q first seen from %s, presented from %s", ErrTokenReplayed, claims.ID, first, source)
+	}
+	identity.ReplaySource = &first
+	return nil
+}
+
+// checkLegacyToken enforces the maximum age of a legacy token accepted from a
+// cluster.
+func (v *JWKSValidator) checkLegacyToken(ctx context.Context, cluster *config.ClusterConfig, identity *ServiceAccountIdentity, now time.Time) error {
+	v.mu.RLock()
+	client, ok := v.workloadClients[cluster.Name]
+	v.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("no workload client configured for cluster %s", cluster.Name)
+	}
+
+	return checkLegacyTokenAge(ctx, client, identity, cluster.LegacyTokens.MaxAge, now)
+}
+
+// verifyBoundPod checks that the pod the token is bound to still exists in the
+// workload cluster with the same UID.
+func (v *JWKSValidator) verifyBoundPod(ctx context.Context, cluster string, identity *ServiceAccountIdentity) error {
+	if identity.Pod == nil || identity.Pod.UID == "" {
+		return fmt.Errorf("token is not bound to a pod")
+	}
+
+	v.mu.RLock()
+	client, ok := v.workloadClients[cluster]
+	v.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("no workload client configured for cluster %s", cluster)
+	}
+
+	pod, err := client.CoreV1().Pods(identity.Namespace).Get(ctx, identity.Pod.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("bound pod %s/%s not found: %w", identity.Namespace, identity.Pod.Name, err)
+	}
+
+	if string(pod.UID) != identity.Pod.UID {
+		return fmt.Errorf("bound pod %s/%s has been recreated: uid %s does not match %s",
+			identity.Namespace, identity.Pod.Name, pod.UID, identity.Pod.UID)
+	}
+
+	return nil
+}
+
 // getJWKS returns the JWKS for a cluster, using cache or fetching as needed.
 func (v *JWKSValidator) getJWKS(ctx context.Context, cluster *config.ClusterConfig) (*jose.JSONWebKeySet, error) {
 	// If inline JWKS data is provided, use it directly
@@ -102,81 +354,300 @@ func (v *JWKSValidator) getJWKS(ctx context.Context, cluster *config.ClusterConf
 		return cluster.JWKSData, nil
 	}
 
+	// Use the last good key set loaded from the JWKS file
+	if cluster.JWKSFile != "" {
+		return v.getFileJWKS(cluster)
+	}
+
+	uri, err := v.jwksURI(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := v.getDocument(ctx, uri, cluster.GetMaxStale(), v.jwksFetcher(cluster, uri))
+	if err != nil {
+		return nil, err
+	}
+	return doc.jwks, nil
+}
+
+// getDocument returns the document cached for a URI, fetching it if the
+// cached copy has expired. An expired document is served at once while within
+// maxStale of its expiry and refreshed in the background, so an issuer that
+// hangs or fails never blocks validation. Documents are only fetched inline
+// when nothing usable is cached.
+func (v *JWKSValidator) getDocument(ctx context.Context, uri string, maxStale time.Duration, fetch documentFetcher) (*cachedDocument, error) {
 	// Check cache first
 	v.mu.RLock()
-	cached, ok := v.cache[cluster.JWKSURI]
+	cached, ok := v.cache[uri]
 	v.mu.RUnlock()
 
-	// Use cached JWKS if it's fresh (less than 1 hour old)
-	if ok && time.Since(cached.fetchedAt) < time.Hour {
-		return cached.jwks, nil
+	// Use the cached document if it's fresh
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached, nil
+	}
+
+	// Serve the last good document while within the max-stale window
+	if ok && time.Since(cached.expiresAt) < maxStale {
+		v.refreshDocumentAsync(ctx, uri, fetch)
+		return cached, nil
 	}
 
-	// Fetch JWKS from URI
-	jwks, err := v.fetchJWKS(ctx, cluster.JWKSURI)
+	// Don't fetch inline while the last failure backs off, so requests
+	// during an outage don't each fetch the document
+	if err := v.fetchBackoff(uri); err != nil {
+		return nil, err
+	}
+
+	return v.refreshDocument(ctx, uri, fetch)
+}
+
+// fetchBackoff returns the last fetch error of a URI if its backoff hasn't
+// elapsed and no fetch is outstanding, or nil if it may be fetched.
+func (v *JWKSValidator) fetchBackoff(uri string) error {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	if _, ok := v.inflight[uri]; ok {
+		return nil
+	}
+	failure, ok := v.fetchFailures[uri]
+	if !ok || !time.Now().Before(failure.retryAt) {
+		return nil
+	}
+	return fmt.Errorf("not fetching %s until %s after %d consecutive failures: %w",
+		uri, failure.retryAt.Format(time.RFC3339), failure.count, failure.err)
+}
+
+// refreshDocumentAsync starts a shared refresh of the document at a URI
+// without waiting for it. It does nothing if a refresh is already
+// outstanding, or if the last fetch failed and its backoff hasn't elapsed.
+func (v *JWKSValidator) refreshDocumentAsync(ctx context.Context, uri string, fetch documentFetcher) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if _, ok := v.inflight[uri]; ok {
+		return
+	}
+	if failure, ok := v.fetchFailures[uri]; ok && time.Now().Before(failure.retryAt) {
+		return
+	}
+
+	call := &inflightFetch{done: make(chan struct{})}
+	v.inflight[uri] = call
+	go v.doRefreshDocument(context.WithoutCancel(ctx), uri, fetch, call)
+}
+
+// refetchJWKS forces a JWKS refetch for a cluster whose cached key set
+// doesn't contain a token's key ID. Forced refetches are limited to one per
+// minRefetchInterval per cluster so random key IDs can't cause a fetch storm.
+// When rate limited, the cached key set is returned unchanged.
+func (v *JWKSValidator) refetchJWKS(ctx context.Context, cluster *config.ClusterConfig) (*jose.JSONWebKeySet, error) {
+	uri, err := v.jwksURI(ctx, cluster)
 	if err != nil {
 		return nil, err
 	}
 
-	// Update cache
 	v.mu.Lock()
-	v.cache[cluster.JWKSURI] = &cachedJWKS{
-		jwks:      jwks,
-		fetchedAt: time.Now(),
+	if time.Since(v.lastRefetch[cluster.Name]) < v.minRefetchInterval {
+		cached, ok := v.cache[uri]
+		v.mu.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("refetch rate limited for cluster %s", cluster.Name)
+		}
+		return cached.jwks, nil
 	}
+	v.lastRefetch[cluster.Name] = time.Now()
 	v.mu.Unlock()
 
-	return jwks, nil
+	return v.refreshJWKS(ctx, cluster, uri)
 }
 
-// fetchJWKS fetches a JWKS from a URI.
-func (v *JWKSValidator) fetchJWKS(ctx context.Context, uri string) (*jose.JSONWebKeySet, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+// jwksURI resolves the JWKS URI for a cluster, using discovery if enabled.
+// The URI is rewritten to the workload API server if workload access is configured.
+func (v *JWKSValidator) jwksURI(ctx context.Context, cluster *config.ClusterConfig) (string, error) {
+	uri := cluster.JWKSURI
+	if cluster.Discovery {
+		doc, err := v.getDiscovery(ctx, cluster)
+		if err != nil {
+			return "", err
+		}
+		uri = doc.JWKSURI
 	}
 
-	resp, err := v.client.Do(req)
+	return v.resolveURL(cluster.Name, uri)
+}
+
+// refreshJWKS fetches the JWKS of a cluster from a URI and updates the cache.
+func (v *JWKSValidator) refreshJWKS(ctx context.Context, cluster *config.ClusterConfig, uri string) (*jose.JSONWebKeySet, error) {
+	doc, err := v.refreshDocument(ctx, uri, v.jwksFetcher(cluster, uri))
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+		return nil, err
+	}
+	return doc.jwks, nil
+}
+
+// jwksFetcher returns a documentFetcher for the JWKS of a cluster at a URI.
+func (v *JWKSValidator) jwksFetcher(cluster *config.ClusterConfig, uri string) documentFetcher {
+	return func(ctx context.Context) (*cachedDocument, time.Duration, error) {
+		f, err := v.fetcherFor(cluster)
+		if err != nil {
+			return nil, 0, err
+		}
+		jwks, ttl, err := fetchJWKS(ctx, f, uri)
+		if err != nil {
+			return nil, 0, err
+		}
+		return &cachedDocument{jwks: jwks}, ttl, nil
+	}
+}
+
+// refreshDocument fetches the document at a URI and updates the cache.
+//
+// Concurrent refreshes of the same URI share a single outstanding fetch.
+// The shared fetch is detached from the cancellation of any one caller, so a
+// cancelled caller returns its context error without failing the others.
+func (v *JWKSValidator) refreshDocument(ctx context.Context, uri string, fetch documentFetcher) (*cachedDocument, error) {
+	v.mu.Lock()
+	call, ok := v.inflight[uri]
+	if !ok {
+		call = &inflightFetch{done: make(chan struct{})}
+		v.inflight[uri] = call
+		go v.doRefreshDocument(context.WithoutCancel(ctx), uri, fetch, call)
+	}
+	v.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.doc, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
-	defer resp.Body.Close()
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch JWKS: HTTP %d", resp.StatusCode)
+// doRefreshDocument performs a shared fetch, updates the cache and publishes
+// the result to all waiters.
+func (v *JWKSValidator) doRefreshDocument(ctx context.Context, uri string, fetch documentFetcher, call *inflightFetch) {
+	doc, ttl, err := fetch(ctx)
+
+	v.mu.Lock()
+	now := time.Now()
+	if err == nil {
+		doc.fetchedAt = now
+		doc.expiresAt = now.Add(ttl)
+		v.cache[uri] = doc
+		delete(v.fetchFailures, uri)
+	} else {
+		failure, ok := v.fetchFailures[uri]
+		if !ok {
+			failure = &fetchFailure{}
+			v.fetchFailures[uri] = failure
+		}
+		failure.count++
+		failure.err = err
+		failure.retryAt = now.Add(refreshBackoff(failure.count))
 	}
+	delete(v.inflight, uri)
+	v.mu.Unlock()
 
-	body, err := io.ReadAll(resp.Body)
+	call.doc, call.err = doc, err
+	close(call.done)
+}
+
+// fetchJWKS fetches a JWKS from a URI and returns it with its cache lifetime.
+// The URI may also serve a SPIFFE trust bundle, whose refresh hint takes
+// precedence over the Cache-Control header.
+func fetchJWKS(ctx context.Context, f *fetcher, uri string) (*jose.JSONWebKeySet, time.Duration, error) {
+	var bundle struct {
+		jose.JSONWebKeySet
+		RefreshHint int64 `json:"spiffe_refresh_hint"`
+	}
+	header, err := f.fetchJSON(ctx, uri, &bundle)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, 0, fmt.Errorf("failed to fetch JWKS: %w", err)
 	}
 
-	var jwks jose.JSONWebKeySet
-	if err := json.Unmarshal(body, &jwks); err != nil {
-		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	ttl := cacheTTL(header)
+	if bundle.RefreshHint > 0 {
+		hint := time.Duration(bundle.RefreshHint) * time.Second
+		ttl = min(max(hint, minJWKSCacheTTL), maxJWKSCacheTTL)
 	}
 
-	return &jwks, nil
+	return &bundle.JSONWebKeySet, ttl, nil
 }
 
+// cacheTTL returns the cache lifetime for a response based on its
+// Cache-Control header, bounded by minJWKSCacheTTL and maxJWKSCacheTTL.
+// Returns jwksCacheTTL if no max-age is present.
+func cacheTTL(header http.Header) time.Duration {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.TrimSpace(strings.ToLower(directive))
+
+		if directive == "no-cache" || directive == "no-store" {
+			return minJWKSCacheTTL
+		}
+
+		value, ok := strings.CutPrefix(directive, "max-age=")
+		if !ok {
+			continue
+		}
+		seconds, err := strconv.Atoi(value)
+		if err != nil {
+			continue
+		}
+
+		ttl := time.Duration(seconds) * time.Second
+		return min(max(ttl, minJWKSCacheTTL), maxJWKSCacheTTL)
+	}
+
+	return jwksCacheTTL
+}
+
+// Default claim paths for service account identity. Projected service account
+// tokens nest the identity under a "kubernetes.io" object; older tokens use
+// flat claim names such as "kubernetes.io/serviceaccount/namespace".
+var (
+	nestedNamespacePath = []string{"kubernetes.io", "namespace"}
+	nestedNamePath      = []string{"kubernetes.io", "serviceaccount", "name"}
+	nestedUIDPath       = []string{"kubernetes.io", "serviceaccount", "uid"}
+
+	flatNamespacePath = []string{"kubernetes.io/serviceaccount/namespace"}
+	flatNamePath      = []string{"kubernetes.io/serviceaccount/service-account.name"}
+	flatUIDPath       = []string{"kubernetes.io/serviceaccount/service-account.uid"}
+)
+
 // extractServiceAccountIdentity extracts the service account identity from JWT claims.
-func extractServiceAccountIdentity(claims *jwt.Claims, k8sClaims map[string]interface{}) (*ServiceAccountIdentity, error) {
-	// Extract namespace from kubernetes.io/serviceaccount/namespace claim
-	namespace, ok := k8sClaims["kubernetes.io/serviceaccount/namespace"].(string)
+// If paths is nil or a path is unset, both the nested and flat claim layouts are accepted.
+func extractServiceAccountIdentity(claims *jwt.Claims, k8sClaims map[string]interface{}, paths *config.ClaimPaths) (*ServiceAccountIdentity, error) {
+	var namespacePaths, namePaths, uidPaths [][]string
+	if paths != nil {
+		namespacePaths = overridePath(paths.Namespace)
+		namePaths = overridePath(paths.Name)
+		uidPaths = overridePath(paths.UID)
+	}
+	if namespacePaths == nil {
+		namespacePaths = [][]string{nestedNamespacePath, flatNamespacePath}
+	}
+	if namePaths == nil {
+		namePaths = [][]string{nestedNamePath, flatNamePath}
+	}
+	if uidPaths == nil {
+		uidPaths = [][]string{nestedUIDPath, flatUIDPath}
+	}
+
+	namespace, ok := lookupStringClaim(k8sClaims, namespacePaths)
 	if !ok {
-		return nil, fmt.Errorf("missing or invalid kubernetes.io/serviceaccount/namespace claim")
+		return nil, fmt.Errorf("missing or invalid service account namespace claim")
 	}
 
-	// Extract service account name from kubernetes.io/serviceaccount/service-account.name claim
-	name, ok := k8sClaims["kubernetes.io/serviceaccount/service-account.name"].(string)
+	name, ok := lookupStringClaim(k8sClaims, namePaths)
 	if !ok {
-		return nil, fmt.Errorf("missing or invalid kubernetes.io/serviceaccount/service-account.name claim")
+		return nil, fmt.Errorf("missing or invalid service account name claim")
 	}
 
-	// Extract UID from kubernetes.io/serviceaccount/service-account.uid claim
-	uid, ok := k8sClaims["kubernetes.io/serviceaccount/service-account.uid"].(string)
+	uid, ok := lookupStringClaim(k8sClaims, uidPaths)
 	if !ok {
-		return nil, fmt.Errorf("missing or invalid kubernetes.io/serviceaccount/service-account.uid claim")
+		return nil, fmt.Errorf("missing or invalid service account uid claim")
 	}
 
 	// Construct username in the standard Kubernetes format
@@ -187,5 +658,62 @@ func extractServiceAccountIdentity(claims *jwt.Claims, k8sClaims map[string]inte
 		Name:      name,
 		UID:       uid,
 		Username:  username,
+		Pod:       extractBoundObject(k8sClaims, "pod"),
+		Node:      extractBoundObject(k8sClaims, "node"),
+		Secret:    extractBoundObject(k8sClaims, "secret"),
 	}, nil
 }
+
+// extractBoundObject extracts the object a projected token is bound to from
+// the nested "kubernetes.io" claims, e.g. kubernetes.io.pod.{name,uid}.
+// Legacy secret-based tokens carry the secret under flat claim names.
+// Returns nil if the token is not bound to an object of this kind.
+func extractBoundObject(k8sClaims map[string]interface{}, kind string) *BoundObject {
+	name, ok := lookupStringClaim(k8sClaims, [][]string{
+		{"kubernetes.io", kind, "name"},
+		{"kubernetes.io/serviceaccount/" + kind + ".name"},
+	})
+	if !ok {
+		return nil
+	}
+
+	uid, _ := lookupStringClaim(k8sClaims, [][]string{
+		{"kubernetes.io", kind, "uid"},
+		{"kubernetes.io/serviceaccount/" + kind + ".uid"},
+	})
+
+	return &BoundObject{Name: name, UID: uid}
+}
+
+// overridePath returns path as the only candidate, or nil if path is unset.
+func overridePath(path []string) [][]string {
+	if len(path) == 0 {
+		return nil
+	}
+	return [][]string{path}
+}
+
+// lookupStringClaim returns the first non-empty string value found at any of
+// the candidate paths.
+func lookupStringClaim(claims map[string]interface{}, candidates [][]string) (string, bool) {
+	for _, path := range candidates {
+		if value, ok := lookupClaim(claims, path).(string); ok && value != "" {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// lookupClaim traverses nested claim objects following path.
+// Returns nil if any key along the path is missing.
+func lookupClaim(claims map[string]interface{}, path []string) interface{} {
+	var current interface{} = claims
+	for _, key := range path {
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = obj[key]
+	}
+	return current
+}
@@ -3,13 +3,23 @@ package token
 import (
 	"context"
 	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/go-jose/go-jose/v4"
+	jwtv5 "github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/holos-run/tokensmith/internal/config"
 	"github.com/holos-run/tokensmith/internal/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 // Helper function to convert RSA public key to JWKS format
@@ -56,16 +66,21 @@ func TestJWKSValidator_Validate(t *testing.T) {
 	cfg := &config.ClustersConfig{
 		Clusters: []config.ClusterConfig{
 			{
-				Name:     "cluster1",
-				Issuer:   issuer1,
-				JWKSData: jwks1,
+				Name:      "cluster1",
+				Issuer:    issuer1,
+				Audiences: []string{"tokensmith"},
+				JWKSData:  jwks1,
 			},
 			{
-				Name:     "cluster2",
-				Issuer:   issuer2,
-				JWKSData: jwks2,
+				Name:      "cluster2",
+				Issuer:    issuer2,
+				Audiences: []string{"tokensmith"},
+				JWKSData:  jwks2,
 			},
 		},
+		ExchangeProfiles: []config.ExchangeProfile{
+			{Name: "vault", Audiences: []string{"vault"}},
+		},
 	}
 
 	// Create validator
@@ -77,7 +92,7 @@ func TestJWKSValidator_Validate(t *testing.T) {
 			"default",
 			"my-service-account",
 			uuid.New().String(),
-			[]string{"https://kubernetes.default.svc"},
+			[]string{"tokensmith"},
 			time.Now().Add(1*time.Hour),
 		)
 		if err != nil {
@@ -102,13 +117,39 @@ func TestJWKSValidator_Validate(t *testing.T) {
 		}
 	})
 
+	t.Run("validate token with nested claims", func(t *testing.T) {
+		// Generate a projected token using the nested kubernetes.io claims
+		token, err := signer1.GenerateToken(
+			"default",
+			"my-service-account",
+			uuid.New().String(),
+			[]string{"tokensmith"},
+			time.Now().Add(1*time.Hour),
+		)
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+
+		identity, err := validator.Validate(ctx, token)
+		if err != nil {
+			t.Fatalf("Validation failed: %v", err)
+		}
+
+		if identity.Namespace != "default" {
+			t.Errorf("Expected namespace 'default', got %q", identity.Namespace)
+		}
+		if identity.Name != "my-service-account" {
+			t.Errorf("Expected name 'my-service-account', got %q", identity.Name)
+		}
+	})
+
 	t.Run("validate token from cluster2", func(t *testing.T) {
 		// Generate a valid token from cluster2 using flat claims
 		token, err := signer2.GenerateTokenFlatClaims(
 			"kube-system",
 			"admin-sa",
 			uuid.New().String(),
-			[]string{"https://kubernetes.default.svc"},
+			[]string{"tokensmith"},
 			time.Now().Add(1*time.Hour),
 		)
 		if err != nil {
@@ -141,7 +182,7 @@ func TestJWKSValidator_Validate(t *testing.T) {
 			"default",
 			"test-sa",
 			uuid.New().String(),
-			[]string{"https://kubernetes.default.svc"},
+			[]string{"tokensmith"},
 			time.Now().Add(1*time.Hour),
 		)
 		if err != nil {
@@ -164,7 +205,7 @@ func TestJWKSValidator_Validate(t *testing.T) {
 			"default",
 			"test-sa",
 			uuid.New().String(),
-			[]string{"https://kubernetes.default.svc"},
+			[]string{"tokensmith"},
 			time.Now().Add(-1*time.Hour), // Expired 1 hour ago
 		)
 		if err != nil {
@@ -189,7 +230,7 @@ func TestJWKSValidator_Validate(t *testing.T) {
 			"default",
 			"test-sa",
 			uuid.New().String(),
-			[]string{"https://kubernetes.default.svc"},
+			[]string{"tokensmith"},
 			time.Now().Add(1*time.Hour),
 		)
 		if err != nil {
@@ -203,6 +244,63 @@ func TestJWKSValidator_Validate(t *testing.T) {
 		}
 	})
 
+	t.Run("reject token with unexpected audience", func(t *testing.T) {
+		token, err := signer1.GenerateTokenFlatClaims(
+			"default",
+			"test-sa",
+			uuid.New().String(),
+			[]string{"https://other-service.example.com"},
+			time.Now().Add(1*time.Hour),
+		)
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+
+		// Validation should fail because the audience is not allowed
+		_, err = validator.Validate(ctx, token)
+		if err == nil {
+			t.Fatal("Expected validation to fail for unexpected audience")
+		}
+	})
+
+	t.Run("reject token carrying management audience", func(t *testing.T) {
+		token, err := signer1.GenerateTokenFlatClaims(
+			"default",
+			"test-sa",
+			uuid.New().String(),
+			[]string{"tokensmith", "https://kubernetes.default.svc"},
+			time.Now().Add(1*time.Hour),
+		)
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+
+		// Validation should fail to prevent exchange loops
+		_, err = validator.Validate(ctx, token)
+		if err == nil {
+			t.Fatal("Expected validation to fail for management audience")
+		}
+	})
+
+	t.Run("reject token carrying exchange profile audience", func(t *testing.T) {
+		token, err := signer1.GenerateTokenFlatClaims(
+			"default",
+			"test-sa",
+			uuid.New().String(),
+			[]string{"tokensmith", "vault"},
+			time.Now().Add(1*time.Hour),
+		)
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+
+		// Tokens issued with an exchange profile must not be exchanged again
+		_, err = validator.Validate(ctx, token)
+		if err == nil {
+			t.Fatal("Expected validation to fail for exchange profile audience")
+		}
+	})
+
 	t.Run("reject malformed token", func(t *testing.T) {
 		// Validation should fail for malformed token
 		_, err := validator.Validate(ctx, "not.a.valid.jwt")
@@ -238,9 +336,10 @@ func TestJWKSValidator_MultipleKeys(t *testing.T) {
 	cfg := &config.ClustersConfig{
 		Clusters: []config.ClusterConfig{
 			{
-				Name:     "cluster",
-				Issuer:   issuer,
-				JWKSData: jwks,
+				Name:      "cluster",
+				Issuer:    issuer,
+				Audiences: []string{"tokensmith"},
+				JWKSData:  jwks,
 			},
 		},
 	}
@@ -252,7 +351,7 @@ func TestJWKSValidator_MultipleKeys(t *testing.T) {
 			"default",
 			"test-sa",
 			uuid.New().String(),
-			[]string{"https://kubernetes.default.svc"},
+			[]string{"tokensmith"},
 			time.Now().Add(1*time.Hour),
 		)
 		if err != nil {
@@ -274,7 +373,7 @@ func TestJWKSValidator_MultipleKeys(t *testing.T) {
 			"default",
 			"test-sa",
 			uuid.New().String(),
-			[]string{"https://kubernetes.default.svc"},
+			[]string{"tokensmith"},
 			time.Now().Add(1*time.Hour),
 		)
 		if err != nil {
@@ -294,20 +393,38 @@ func TestJWKSValidator_MultipleKeys(t *testing.T) {
 
 func TestExtractServiceAccountIdentity(t *testing.T) {
 	tests := []struct {
-		name        string
-		k8sClaims   map[string]interface{}
-		wantErr     bool
-		wantNS      string
-		wantName    string
-		wantUID     string
-		wantUser    string
+		name      string
+		k8sClaims map[string]interface{}
+		paths     *config.ClaimPaths
+		wantErr   bool
+		wantNS    string
+		wantName  string
+		wantUID   string
+		wantUser  string
 	}{
 		{
 			name: "valid claims",
 			k8sClaims: map[string]interface{}{
-				"kubernetes.io/serviceaccount/namespace":              "default",
-				"kubernetes.io/serviceaccount/service-account.name":   "my-sa",
-				"kubernetes.io/serviceaccount/service-account.uid":    "123e4567-e89b-12d3-a456-426614174000",
+				"kubernetes.io/serviceaccount/namespace":            "default",
+				"kubernetes.io/serviceaccount/service-account.name": "my-sa",
+				"kubernetes.io/serviceaccount/service-account.uid":  "123e4567-e89b-12d3-a456-426614174000",
+			},
+			wantErr:  false,
+			wantNS:   "default",
+			wantName: "my-sa",
+			wantUID:  "123e4567-e89b-12d3-a456-426614174000",
+			wantUser: "system:serviceaccount:default:my-sa",
+		},
+		{
+			name: "valid nested claims",
+			k8sClaims: map[string]interface{}{
+				"kubernetes.io": map[string]interface{}{
+					"namespace": "default",
+					"serviceaccount": map[string]interface{}{
+						"name": "my-sa",
+						"uid":  "123e4567-e89b-12d3-a456-426614174000",
+					},
+				},
 			},
 			wantErr:  false,
 			wantNS:   "default",
@@ -315,6 +432,49 @@ func TestExtractServiceAccountIdentity(t *testing.T) {
 			wantUID:  "123e4567-e89b-12d3-a456-426614174000",
 			wantUser: "system:serviceaccount:default:my-sa",
 		},
+		{
+			name: "custom claim paths",
+			k8sClaims: map[string]interface{}{
+				"k8s": map[string]interface{}{
+					"ns": "team-a",
+					"sa": map[string]interface{}{
+						"name": "builder",
+					},
+				},
+				"kubernetes.io/serviceaccount/service-account.uid": "456",
+			},
+			paths: &config.ClaimPaths{
+				Namespace: []string{"k8s", "ns"},
+				Name:      []string{"k8s", "sa", "name"},
+			},
+			wantErr:  false,
+			wantNS:   "team-a",
+			wantName: "builder",
+			wantUID:  "456",
+			wantUser: "system:serviceaccount:team-a:builder",
+		},
+		{
+			name: "custom claim path does not fall back to defaults",
+			k8sClaims: map[string]interface{}{
+				"kubernetes.io/serviceaccount/namespace":            "default",
+				"kubernetes.io/serviceaccount/service-account.name": "my-sa",
+				"kubernetes.io/serviceaccount/service-account.uid":  "123",
+			},
+			paths: &config.ClaimPaths{
+				Namespace: []string{"k8s", "ns"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "nested claims with invalid type",
+			k8sClaims: map[string]interface{}{
+				"kubernetes.io": map[string]interface{}{
+					"namespace":      "default",
+					"serviceaccount": "my-sa",
+				},
+			},
+			wantErr: true,
+		},
 		{
 			name: "missing namespace claim",
 			k8sClaims: map[string]interface{}{
@@ -354,7 +514,7 @@ func TestExtractServiceAccountIdentity(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// We need to pass a jwt.Claims, but it's not used in extractServiceAccountIdentity
 			// so we can pass nil
-			identity, err := extractServiceAccountIdentity(nil, tt.k8sClaims)
+			identity, err := extractServiceAccountIdentity(nil, tt.k8sClaims, tt.paths)
 
 			if tt.wantErr {
 				if err == nil {
@@ -382,3 +542,330 @@ func TestExtractServiceAccountIdentity(t *testing.T) {
 		})
 	}
 }
+
+func TestJWKSValidator_VerifyBoundPod(t *testing.T) {
+	ctx := context.Background()
+	issuer := "https://cluster.example.com"
+
+	signer, err := testutil.NewJWTSigner(issuer)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	cfg := &config.ClustersConfig{
+		Clusters: []config.ClusterConfig{
+			{
+				Name:           "cluster",
+				Issuer:         issuer,
+				Audiences:      []string{"tokensmith"},
+				JWKSData:       createJWKS(signer.PublicKey(), signer.KeyID()),
+				Kubeconfig:     "/etc/tokensmith/cluster.kubeconfig",
+				VerifyBoundPod: true,
+			},
+		},
+	}
+
+	// The workload cluster has a single running pod
+	workloadClient := fake.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "my-pod",
+			Namespace: "default",
+			UID:       types.UID("pod-uid-1"),
+		},
+	})
+
+	validator := NewJWKSValidator(cfg)
+	validator.SetWorkloadClient("cluster", workloadClient)
+
+	generateToken := func(pod map[string]interface{}) string {
+		kubernetesClaims := map[string]interface{}{
+			"namespace": "default",
+			"serviceaccount": map[string]interface{}{
+				"name": "my-sa",
+				"uid":  "sa-uid",
+			},
+			"node": map[string]interface{}{
+				"name": "node-1",
+				"uid":  "node-uid",
+			},
+		}
+		if pod != nil {
+			kubernetesClaims["pod"] = pod
+		}
+
+		token, err := signer.SignClaims(jwtv5.MapClaims{
+			"iss":           issuer,
+			"sub":           "system:serviceaccount:default:my-sa",
+			"aud":           []string{"tokensmith"},
+			"exp":           time.Now().Add(1 * time.Hour).Unix(),
+			"iat":           time.Now().Unix(),
+			"kubernetes.io": kubernetesClaims,
+		})
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+		return token
+	}
+
+	t.Run("accept token bound to live pod", func(t *testing.T) {
+		token := generateToken(map[string]interface{}{"name": "my-pod", "uid": "pod-uid-1"})
+
+		identity, err := validator.Validate(ctx, token)
+		if err != nil {
+			t.Fatalf("Validation failed: %v", err)
+		}
+
+		if identity.Pod == nil || identity.Pod.Name != "my-pod" || identity.Pod.UID != "pod-uid-1" {
+			t.Errorf("Expected pod binding my-pod/pod-uid-1, got %+v", identity.Pod)
+		}
+		if identity.Node == nil || identity.Node.Name != "node-1" || identity.Node.UID != "node-uid" {
+			t.Errorf("Expected node binding node-1/node-uid, got %+v", identity.Node)
+		}
+		if identity.Secret != nil {
+			t.Errorf("Expected no secret binding, got %+v", identity.Secret)
+		}
+	})
+
+	t.Run("reject token bound to recreated pod", func(t *testing.T) {
+		token := generateToken(map[string]interface{}{"name": "my-pod", "uid": "pod-uid-0"})
+
+		if _, err := validator.Validate(ctx, token); err == nil {
+			t.Fatal("Expected validation to fail for recreated pod")
+		}
+	})
+
+	t.Run("reject token bound to deleted pod", func(t *testing.T) {
+		token := generateToken(map[string]interface{}{"name": "deleted-pod", "uid": "pod-uid-2"})
+
+		if _, err := validator.Validate(ctx, token); err == nil {
+			t.Fatal("Expected validation to fail for deleted pod")
+		}
+	})
+
+	t.Run("reject token not bound to a pod", func(t *testing.T) {
+		token := generateToken(nil)
+
+		if _, err := validator.Validate(ctx, token); err == nil {
+			t.Fatal("Expected validation to fail for token without pod binding")
+		}
+	})
+}
+
+func TestJWKSValidator_KeyRotation(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		mu      sync.Mutex
+		current *jose.JSONWebKeySet
+		fetches int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		fetches++
+		w.Header().Set("Cache-Control", "max-age=300")
+		writeJSON(w, current)
+	}))
+	defer server.Close()
+
+	issuer := "https://cluster.example.com"
+	oldSigner, err := testutil.NewJWTSigner(issuer)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	newSigner, err := testutil.NewJWTSigner(issuer)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	current = createJWKS(oldSigner.PublicKey(), oldSigner.KeyID())
+
+	cfg := &config.ClustersConfig{
+		Clusters: []config.ClusterConfig{
+			{
+				Name:      "cluster",
+				Issuer:    issuer,
+				Audiences: []string{"tokensmith"},
+				JWKSURI:   server.URL,
+			},
+		},
+	}
+	validator := NewJWKSValidator(cfg)
+	validator.minRefetchInterval = time.Hour
+
+	generateToken := func(signer *testutil.JWTSigner) string {
+		token, err := signer.GenerateToken(
+			"default",
+			"my-sa",
+			uuid.New().String(),
+			[]string{"tokensmith"},
+			time.Now().Add(1*time.Hour),
+		)
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+		return token
+	}
+
+	fetchCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return fetches
+	}
+
+	t.Run("cache lifetime honors max-age", func(t *testing.T) {
+		if _, err := validator.Validate(ctx, generateToken(oldSigner)); err != nil {
+			t.Fatalf("Validation failed: %v", err)
+		}
+
+		validator.mu.RLock()
+		cached := validator.cache[server.URL]
+		validator.mu.RUnlock()
+
+		if ttl := cached.expiresAt.Sub(cached.fetchedAt); ttl != 300*time.Second {
+			t.Errorf("Expected cache TTL 300s, got %v", ttl)
+		}
+	})
+
+	t.Run("refetch on unknown key ID after rotation", func(t *testing.T) {
+		mu.Lock()
+		current = createJWKS(newSigner.PublicKey(), newSigner.KeyID())
+		mu.Unlock()
+		before := fetchCount()
+
+		if _, err := validator.Validate(ctx, generateToken(newSigner)); err != nil {
+			t.Fatalf("Validation failed after key rotation: %v", err)
+		}
+		if got := fetchCount() - before; got != 1 {
+			t.Errorf("Expected 1 refetch, got %d", got)
+		}
+	})
+
+	t.Run("rate limit refetches for unknown key IDs", func(t *testing.T) {
+		before := fetchCount()
+
+		for i := 0; i < 5; i++ {
+			randomSigner, err := testutil.NewJWTSignerWithKeyID(issuer, uuid.New().String())
+			if err != nil {
+				t.Fatalf("Failed to create signer: %v", err)
+			}
+			if _, err := validator.Validate(ctx, generateToken(randomSigner)); err == nil {
+				t.Fatal("Expected validation to fail for unknown key ID")
+			}
+		}
+
+		if got := fetchCount() - before; got != 0 {
+			t.Errorf("Expected no refetches within the minimum interval, got %d", got)
+		}
+	})
+}
+
+func TestCacheTTL(t *testing.T) {
+	tests := []struct {
+		name         string
+		cacheControl string
+		want         time.Duration
+	}{
+		{name: "no header", cacheControl: "", want: jwksCacheTTL},
+		{name: "max-age", cacheControl: "public, max-age=600", want: 10 * time.Minute},
+		{name: "max-age below minimum", cacheControl: "max-age=5", want: minJWKSCacheTTL},
+		{name: "max-age above maximum", cacheControl: "max-age=31536000", want: maxJWKSCacheTTL},
+		{name: "no-store", cacheControl: "no-store", want: minJWKSCacheTTL},
+		{name: "invalid max-age", cacheControl: "max-age=abc", want: jwksCacheTTL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.cacheControl != "" {
+				header.Set("Cache-Control", tt.cacheControl)
+			}
+			if got := cacheTTL(header); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestJWKSValidator_DeduplicateFetches(t *testing.T) {
+	issuer := "https://cluster.example.com"
+
+	signer, err := testutil.NewJWTSigner(issuer)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	// The server blocks every fetch until release is closed
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		<-release
+		writeJSON(w, createJWKS(signer.PublicKey(), signer.KeyID()))
+	}))
+	defer server.Close()
+
+	validator := NewJWKSValidator(&config.ClustersConfig{
+		Clusters: []config.ClusterConfig{
+			{
+				Name:      "cluster",
+				Issuer:    issuer,
+				Audiences: []string{"tokensmith"},
+				JWKSURI:   server.URL,
+			},
+		},
+	})
+
+	token, err := signer.GenerateToken("default", "my-sa", uuid.New().String(),
+		[]string{"tokensmith"}, time.Now().Add(1*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	// One caller gives up before the fetch completes
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancelledErr := make(chan error, 1)
+	go func() {
+		_, err := validator.Validate(cancelledCtx, token)
+		cancelledErr <- err
+	}()
+
+	const callers = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := validator.Validate(context.Background(), token)
+			errs <- err
+		}()
+	}
+
+	// Wait for the shared fetch to start, then cancel one caller
+	for fetches.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-cancelledErr; err == nil {
+		t.Error("Expected cancelled caller to fail")
+	}
+
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Validation failed: %v", err)
+		}
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("Expected 1 fetch, got %d", got)
+	}
+}
+
+// writeJSON writes v to w as an application/json response.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,108 @@
+package token
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/holos-run/tokensmith/internal/config"
+)
+
+// jwksFilePollInterval is how often JWKS files are checked for changes.
+// Polling the content handles ConfigMap volumes, which are updated by
+// atomically swapping a symlink rather than writing the file in place.
+const jwksFilePollInterval = 10 * time.Second
+
+// fileJWKS holds a key set loaded from a JWKS file with the raw content it
+// was parsed from.
+type fileJWKS struct {
+	jwks *jose.JSONWebKeySet
+	data []byte
+}
+
+// getFileJWKS returns the last good key set loaded from a cluster's JWKS
+// file, loading it on first use.
+func (v *JWKSValidator) getFileJWKS(cluster *config.ClusterConfig) (*jose.JSONWebKeySet, error) {
+	v.mu.RLock()
+	cached, ok := v.fileCache[cluster.Name]
+	v.mu.RUnlock()
+	if ok {
+		return cached.jwks, nil
+	}
+
+	if _, err := v.reloadJWKSFile(cluster); err != nil {
+		return nil, err
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.fileCache[cluster.Name].jwks, nil
+}
+
+// reloadJWKSFile reads a cluster's JWKS file and atomically replaces the
+// cached key set if the content changed. If the file can't be read or
+// parsed, the last good key set stays in use and an error is returned.
+// Reports whether the key set was replaced.
+func (v *JWKSValidator) reloadJWKSFile(cluster *config.ClusterConfig) (bool, error) {
+	data, err := os.ReadFile(cluster.JWKSFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+
+	v.mu.RLock()
+	cached, ok := v.fileCache[cluster.Name]
+	v.mu.RUnlock()
+	if ok && bytes.Equal(cached.data, data) {
+		return false, nil
+	}
+
+	var jwks jose.JSONWebKeySet
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return false, fmt.Errorf("failed to parse JWKS file %s: %w", cluster.JWKSFile, err)
+	}
+	if len(jwks.Keys) == 0 {
+		return false, fmt.Errorf("JWKS file %s contains no keys", cluster.JWKSFile)
+	}
+
+	v.mu.Lock()
+	v.fileCache[cluster.Name] = &fileJWKS{
+		jwks: &jwks,
+		data: data,
+	}
+	v.mu.Unlock()
+
+	return true, nil
+}
+
+// watchJWKSFile polls a cluster's JWKS file and reloads it when it changes.
+func (v *JWKSValidator) watchJWKSFile(ctx context.Context, cluster *config.ClusterConfig, logger *slog.Logger) {
+	ticker := time.NewTicker(jwksFilePollInterval)
+	defer ticker.Stop()
+
+	for {
+		changed, err := v.reloadJWKSFile(cluster)
+		if err != nil {
+			logger.Warn("JWKS file reload failed, keeping last good key set",
+				slog.String("cluster", cluster.Name),
+				slog.String("path", cluster.JWKSFile),
+				slog.String("error", err.Error()),
+			)
+		} else if changed {
+			logger.Info("JWKS file loaded",
+				slog.String("cluster", cluster.Name),
+				slog.String("path", cluster.JWKSFile),
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/holos-run/tokensmith/internal/config"
+	"github.com/holos-run/tokensmith/internal/testutil"
+)
+
+// writeJWKSFile writes the public key of signer as a JWKS file.
+func writeJWKSFile(t *testing.T, path string, signer *testutil.JWTSigner) {
+	t.Helper()
+	data, err := json.Marshal(createJWKS(signer.PublicKey(), signer.KeyID()))
+	if err != nil {
+		t.Fatalf("Failed to marshal JWKS: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("Failed to write JWKS file: %v", err)
+	}
+}
+
+func TestJWKSValidator_JWKSFile(t *testing.T) {
+	ctx := context.Background()
+	issuer := "https://kubernetes.default.svc.cluster.local"
+
+	oldSigner, err := testutil.NewJWTSigner(issuer)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	newSigner, err := testutil.NewJWTSigner(issuer)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKSFile(t, path, oldSigner)
+
+	cfg := &config.ClustersConfig{
+		Clusters: []config.ClusterConfig{
+			{
+				Name:      "cluster",
+				Issuer:    issuer,
+				Audiences: []string{"tokensmith"},
+				JWKSFile:  path,
+			},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Invalid configuration: %v", err)
+	}
+	validator := NewJWKSValidator(cfg)
+	cluster := &cfg.Clusters[0]
+
+	validate := func(signer *testutil.JWTSigner) error {
+		token, err := signer.GenerateToken("default", "my-sa", uuid.New().String(),
+			[]string{"tokensmith"}, time.Now().Add(1*time.Hour))
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+		_, err = validator.Validate(ctx, token)
+		return err
+	}
+
+	if err := validate(oldSigner); err != nil {
+		t.Fatalf("Validation failed: %v", err)
+	}
+	if err := validate(newSigner); err == nil {
+		t.Fatal("Expected validation to fail for a key not in the file")
+	}
+
+	// An unchanged file is not reloaded
+	changed, err := validator.reloadJWKSFile(cluster)
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if changed {
+		t.Error("Expected unchanged file not to be reloaded")
+	}
+
+	// Rotating the key in the file replaces the key set
+	writeJWKSFile(t, path, newSigner)
+	changed, err = validator.reloadJWKSFile(cluster)
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if !changed {
+		t.Error("Expected rotated file to be reloaded")
+	}
+	if err := validate(newSigner); err != nil {
+		t.Fatalf("Validation failed after rotation: %v", err)
+	}
+	if err := validate(oldSigner); err == nil {
+		t.Fatal("Expected validation to fail for the rotated-out key")
+	}
+
+	// A file that fails to parse keeps the last good key set in use
+	for _, content := range []string{"not json", `{"keys":[]}`} {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write JWKS file: %v", err)
+		}
+		if _, err := validator.reloadJWKSFile(cluster); err == nil {
+			t.Errorf("Expected reload of %q to fail", content)
+		}
+		if err := validate(newSigner); err != nil {
+			t.Errorf("Expected last good key set to stay in use, got: %v", err)
+		}
+	}
+}
+
+func TestJWKSValidator_JWKSFileMissing(t *testing.T) {
+	cfg := &config.ClustersConfig{
+		Clusters: []config.ClusterConfig{
+			{
+				Name:      "cluster",
+				Issuer:    "https://kubernetes.default.svc.cluster.local",
+				Audiences: []string{"tokensmith"},
+				JWKSFile:  filepath.Join(t.TempDir(), "missing.json"),
+			},
+		},
+	}
+	validator := NewJWKSValidator(cfg)
+
+	if _, err := validator.getJWKS(context.Background(), &cfg.Clusters[0]); err == nil {
+		t.Fatal("Expected error for missing JWKS file")
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/holos-run/tokensmith/internal/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -18,6 +21,11 @@ type ClientConfig struct {
 	// UseInClusterForManagement indicates whether to use in-cluster config for
 	// the management cluster. Defaults to true.
 	UseInClusterForManagement bool
+
+	// SkipWorkload skips creating the default workload cluster client, for
+	// multi-cluster configurations where workload cluster clients are added
+	// with AddClusters.
+	SkipWorkload bool
 }
 
 // Clients holds Kubernetes clients for both workload and management clusters.
@@ -27,14 +35,21 @@ type Clients struct {
 
 	// Management is the client for the management cluster (target for token exchange).
 	Management kubernetes.Interface
+
+	// Clusters holds clients for workload clusters indexed by cluster name.
+	Clusters map[string]kubernetes.Interface
 }
 
 // NewClients creates and initializes Kubernetes clients for both clusters.
 func NewClients(ctx context.Context, config ClientConfig) (*Clients, error) {
 	// Initialize workload cluster client
-	workloadClient, err := newWorkloadClient(config.WorkloadKubeconfig)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create workload cluster client: %w", err)
+	var workloadClient kubernetes.Interface
+	if !config.SkipWorkload {
+		var err error
+		workloadClient, err = newWorkloadClient(config.WorkloadKubeconfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create workload cluster client: %w", err)
+		}
 	}
 
 	// Initialize management cluster client
@@ -46,9 +61,72 @@ func NewClients(ctx context.Context, config ClientConfig) (*Clients, error) {
 	return &Clients{
 		Workload:   workloadClient,
 		Management: managementClient,
+		Clusters:   make(map[string]kubernetes.Interface),
 	}, nil
 }
 
+// AddClusters creates clients for workload clusters from their REST configs,
+// indexed by cluster name.
+func (c *Clients) AddClusters(restConfigs map[string]*rest.Config) error {
+	if c.Clusters == nil {
+		c.Clusters = make(map[string]kubernetes.Interface)
+	}
+	for name, restConfig := range restConfigs {
+		client, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			return fmt.Errorf("failed to create client for workload cluster %s: %w", name, err)
+		}
+		c.Clusters[name] = client
+	}
+	return nil
+}
+
+// NewClusterRESTConfigs loads workload cluster REST configs for every cluster
+// with a kubeconfig or kubeconfig Secret configured, indexed by cluster name.
+// Kubeconfig Secrets are read from the management cluster.
+func NewClusterRESTConfigs(ctx context.Context, cfg *config.ClustersConfig, management kubernetes.Interface) (map[string]*rest.Config, error) {
+	configs := make(map[string]*rest.Config)
+	for _, cluster := range cfg.Clusters {
+		var restConfig *rest.Config
+		var err error
+
+		switch {
+		case cluster.Kubeconfig != "":
+			restConfig, err = clientcmd.BuildConfigFromFlags("", cluster.Kubeconfig)
+		case cluster.KubeconfigSecret != nil:
+			restConfig, err = restConfigFromSecret(ctx, management, cluster.KubeconfigSecret)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kubeconfig for cluster %s: %w", cluster.Name, err)
+		}
+
+		configs[cluster.Name] = restConfig
+	}
+	return configs, nil
+}
+
+// restConfigFromSecret loads a REST config from a kubeconfig stored in a Secret.
+func restConfigFromSecret(ctx context.Context, client kubernetes.Interface, ref *config.SecretKeyRef) (*rest.Config, error) {
+	secret, err := client.CoreV1().Secrets(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret %s/%s: %w", ref.Namespace, ref.Name, err)
+	}
+
+	key := ref.Key
+	if key == "" {
+		key = config.DefaultKubeconfigSecretKey
+	}
+
+	data, ok := secret.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("secret %s/%s has no key %q", ref.Namespace, ref.Name, key)
+	}
+
+	return clientcmd.RESTConfigFromKubeConfig(data)
+}
+
 // newWorkloadClient creates a Kubernetes client for the workload cluster.
 func newWorkloadClient(kubeconfigPath string) (kubernetes.Interface, error) {
 	var config *rest.Config
@@ -95,11 +173,16 @@ func newManagementClient(useInCluster bool) (kubernetes.Interface, error) {
 	return client, nil
 }
 
-// HealthCheck verifies connectivity to both clusters.
+// HealthCheck verifies connectivity to the management cluster and the default
+// workload cluster, without which no token can be exchanged. Clusters added
+// with AddClusters are checked separately with CheckClusters, so one
+// unreachable workload cluster doesn't affect the others.
 func (c *Clients) HealthCheck(ctx context.Context) error {
 	// Check workload cluster
-	if _, err := c.Workload.Discovery().ServerVersion(); err != nil {
-		return fmt.Errorf("workload cluster health check failed: %w", err)
+	if c.Workload != nil {
+		if _, err := c.Workload.Discovery().ServerVersion(); err != nil {
+			return fmt.Errorf("workload cluster health check failed: %w", err)
+		}
 	}
 
 	// Check management cluster
@@ -109,3 +192,16 @@ func (c *Clients) HealthCheck(ctx context.Context) error {
 
 	return nil
 }
+
+// CheckClusters verifies connectivity to each workload cluster added with
+// AddClusters and returns the health check errors of unreachable clusters
+// indexed by cluster name.
+func (c *Clients) CheckClusters(ctx context.Context) map[string]error {
+	errs := make(map[string]error)
+	for name, client := range c.Clusters {
+		if _, err := client.Discovery().ServerVersion(); err != nil {
+			errs[name] = fmt.Errorf("workload cluster %s health check failed: %w", name, err)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,184 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/holos-run/tokensmith/internal/config"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: workload
+  cluster:
+    server: https://workload.example.com:6443
+contexts:
+- name: workload
+  context:
+    cluster: workload
+    user: tokensmith
+current-context: workload
+users:
+- name: tokensmith
+  user:
+    token: workload-credentials
+`
+
+func TestNewClusterRESTConfigs(t *testing.T) {
+	ctx := context.Background()
+
+	management := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "workload-kubeconfig",
+			Namespace: "tokensmith-system",
+		},
+		Data: map[string][]byte{
+			"kubeconfig": []byte(testKubeconfig),
+		},
+	})
+
+	cfg := &config.ClustersConfig{
+		Clusters: []config.ClusterConfig{
+			{
+				Name: "from-secret",
+				KubeconfigSecret: &config.SecretKeyRef{
+					Namespace: "tokensmith-system",
+					Name:      "workload-kubeconfig",
+				},
+			},
+			{
+				Name: "no-kubeconfig",
+			},
+		},
+	}
+
+	configs, err := NewClusterRESTConfigs(ctx, cfg, management)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(configs) != 1 {
+		t.Fatalf("Expected 1 REST config, got %d", len(configs))
+	}
+	restConfig := configs["from-secret"]
+	if restConfig == nil {
+		t.Fatal("Expected REST config for cluster from-secret")
+	}
+	if restConfig.Host != "https://workload.example.com:6443" {
+		t.Errorf("Expected host https://workload.example.com:6443, got %q", restConfig.Host)
+	}
+	if restConfig.BearerToken != "workload-credentials" {
+		t.Errorf("Expected bearer token from kubeconfig, got %q", restConfig.BearerToken)
+	}
+
+	t.Run("missing secret key", func(t *testing.T) {
+		cfg.Clusters[0].KubeconfigSecret.Key = "other"
+		defer func() { cfg.Clusters[0].KubeconfigSecret.Key = "" }()
+
+		if _, err := NewClusterRESTConfigs(ctx, cfg, management); err == nil {
+			t.Fatal("Expected error for missing secret key")
+		}
+	})
+}
+
+func TestClients_HealthCheck(t *testing.T) {
+	ctx := context.Background()
+
+	unhealthy := func() *fake.Clientset {
+		client := fake.NewSimpleClientset()
+		client.PrependReactor("get", "version", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, errors.New("connection refused")
+		})
+		return client
+	}
+
+	tests := []struct {
+		name              string
+		management        kubernetes.Interface
+		clusters          map[string]kubernetes.Interface
+		expectError       bool
+		expectUnreachable []string
+	}{
+		{
+			name:       "all clusters healthy",
+			management: fake.NewSimpleClientset(),
+			clusters: map[string]kubernetes.Interface{
+				"cluster-a": fake.NewSimpleClientset(),
+				"cluster-b": fake.NewSimpleClientset(),
+			},
+		},
+		{
+			name:       "unhealthy workload clusters are reported per cluster",
+			management: fake.NewSimpleClientset(),
+			clusters: map[string]kubernetes.Interface{
+				"cluster-a": unhealthy(),
+				"cluster-b": fake.NewSimpleClientset(),
+				"cluster-c": unhealthy(),
+			},
+			expectUnreachable: []string{"cluster-a", "cluster-c"},
+		},
+		{
+			name:       "unhealthy management cluster",
+			management: unhealthy(),
+			clusters: map[string]kubernetes.Interface{
+				"cluster-a": fake.NewSimpleClientset(),
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clients := &Clients{
+				Management: tt.management,
+				Clusters:   tt.clusters,
+			}
+
+			err := clients.HealthCheck(ctx)
+			if tt.expectError && err == nil {
+				t.Error("Expected health check to fail")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+
+			errs := clients.CheckClusters(ctx)
+			if len(errs) != len(tt.expectUnreachable) {
+				t.Errorf("Expected %d unreachable clusters, got %v", len(tt.expectUnreachable), errs)
+			}
+			for _, name := range tt.expectUnreachable {
+				err, ok := errs[name]
+				if !ok {
+					t.Errorf("Expected cluster %s to be reported", name)
+					continue
+				}
+				if !strings.Contains(err.Error(), "workload cluster "+name) {
+					t.Errorf("Expected error to name cluster %s, got %q", name, err.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestClients_AddClusters(t *testing.T) {
+	clients := &Clients{}
+	err := clients.AddClusters(map[string]*rest.Config{
+		"cluster-a": {Host: "https://cluster-a.example.com:6443"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := clients.Clusters["cluster-a"]; !ok {
+		t.Error("Expected client for cluster-a")
+	}
+}
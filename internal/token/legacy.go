@@ -0,0 +1,146 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/holos-run/tokensmith/internal/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// legacyIssuer is the issuer of legacy Secret-based service account tokens,
+// regardless of the cluster's configured service account issuer.
+const legacyIssuer = "kubernetes/serviceaccount"
+
+// TokenKind classifies the tokens identities are validated from.
+type TokenKind string
+
+const (
+	// ProjectedToken is a time-bound token issued by the TokenRequest API.
+	ProjectedToken TokenKind = "projected"
+
+	// LegacyToken is a long-lived token stored in a
+	// kubernetes.io/service-account-token Secret.
+	LegacyToken TokenKind = "legacy"
+
+	// OIDCToken is a token from a non-Kubernetes OIDC issuer mapped to a
+	// service account.
+	OIDCToken TokenKind = "oidc"
+
+	// SPIFFEToken is a JWT-SVID issued to a workload in a SPIFFE trust domain.
+	SPIFFEToken TokenKind = "jwt-svid"
+)
+
+// ErrLegacyToken is returned when a legacy Secret-based service account token
+// is presented but not accepted.
+var ErrLegacyToken = errors.New("legacy service account tokens are not accepted")
+
+// errUnknownLegacyKey is returned when no cluster verifying legacy tokens with
+// its key set has the signing key of a legacy token.
+var errUnknownLegacyKey = errors.New("no cluster accepting legacy tokens has key ID")
+
+// classifyToken reports whether a token is a projected or legacy token.
+// Legacy tokens carry the legacy issuer and flat secret claims, and never
+// carry an expiry.
+func classifyToken(claims *jwt.Claims, k8sClaims map[string]interface{}) TokenKind {
+	if claims.Issuer == legacyIssuer {
+		return LegacyToken
+	}
+	if claims.Expiry == nil && extractBoundObject(k8sClaims, "secret") != nil {
+		if _, nested := k8sClaims["kubernetes.io"]; !nested {
+			return LegacyToken
+		}
+	}
+	return ProjectedToken
+}
+
+// classifyUnverifiedToken classifies a token without verifying its signature.
+// Use it only for tokens authenticated by other means, such as a TokenReview.
+// Tokens that can't be parsed are classified as projected.
+func classifyUnverifiedToken(bearerToken string) (TokenKind, map[string]interface{}) {
+	claims, k8sClaims, err := parseUnverifiedClaims(bearerToken)
+	if err != nil {
+		return ProjectedToken, nil
+	}
+	return classifyToken(claims, k8sClaims), k8sClaims
+}
+
+// parseUnverifiedClaims returns the claims of a token without verifying its
+// signature.
+func parseUnverifiedClaims(bearerToken string) (*jwt.Claims, map[string]interface{}, error) {
+	tok, err := jwt.ParseSigned(bearerToken, config.SupportedSigningAlgorithms)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse token: %w", err)
+	}
+
+	var claims jwt.Claims
+	var k8sClaims map[string]interface{}
+	if err := tok.UnsafeClaimsWithoutVerification(&claims, &k8sClaims); err != nil {
+		return nil, nil, fmt.Errorf("failed to extract claims: %w", err)
+	}
+	return &claims, k8sClaims, nil
+}
+
+// checkLegacyTokenAge verifies that the Secret holding a legacy token still
+// belongs to the token's service account and was created within maxAge.
+// Legacy tokens carry no iat, so the Secret's creation time is their age.
+func checkLegacyTokenAge(ctx context.Context, client kubernetes.Interface, identity *ServiceAccountIdentity, maxAge time.Duration, now time.Time) error {
+	if identity.Secret == nil {
+		return fmt.Errorf("legacy token has no secret claim")
+	}
+
+	secret, err := client.CoreV1().Secrets(identity.Namespace).Get(ctx, identity.Secret.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("legacy token secret %s/%s not found: %w", identity.Namespace, identity.Secret.Name, err)
+	}
+
+	if uid := secret.Annotations["kubernetes.io/service-account.uid"]; uid != identity.UID {
+		return fmt.Errorf("legacy token secret %s/%s belongs to service account uid %q, not %q",
+			identity.Namespace, identity.Secret.Name, uid, identity.UID)
+	}
+
+	if age := now.Sub(secret.CreationTimestamp.Time); age > maxAge {
+		return fmt.Errorf("legacy token age %s exceeds maximum %s", age.Truncate(time.Second), maxAge)
+	}
+
+	return nil
+}
+
+// findLegacyCluster returns the cluster that accepts legacy tokens and whose
+// key set contains kid. Legacy tokens share one issuer across clusters, so
+// the signing key identifies the cluster. If the key set of any such cluster
+// is unavailable, the error wraps ErrKeysUnavailable rather than
+// errUnknownLegacyKey, since the token may belong to that cluster.
+func (v *JWKSValidator) findLegacyCluster(ctx context.Context, kid string) (*config.ClusterConfig, error) {
+	accepted := false
+	var unavailable []error
+	for i := range v.config.Clusters {
+		cluster := &v.config.Clusters[i]
+		if cluster.LegacyTokens == nil || !cluster.UsesJWKS() {
+			continue
+		}
+		accepted = true
+
+		jwks, err := v.getJWKS(ctx, cluster)
+		if err != nil {
+			unavailable = append(unavailable, fmt.Errorf("cluster %s: %w", cluster.Name, err))
+			continue
+		}
+		if kid != "" && len(jwks.Key(kid)) > 0 {
+			return cluster, nil
+		}
+	}
+
+	if !accepted {
+		return nil, ErrLegacyToken
+	}
+	if len(unavailable) > 0 {
+		return nil, fmt.Errorf("%w: no available key set has legacy token key ID %q: %w",
+			ErrKeysUnavailable, kid, errors.Join(unavailable...))
+	}
+	return nil, fmt.Errorf("%w %q", errUnknownLegacyKey, kid)
+}
@@ -0,0 +1,487 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4/jwt"
+	jwtv5 "github.com/golang-jwt/jwt/v5"
+	"github.com/holos-run/tokensmith/internal/config"
+	"github.com/holos-run/tokensmith/internal/testutil"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const legacySAUID = "12345678-1234-1234-1234-123456789012"
+
+// signLegacyToken signs a token with the claims of a legacy Secret-based
+// service account token for default/my-sa stored in default/my-sa-token.
+func signLegacyToken(t *testing.T, signer *testutil.JWTSigner) string {
+	t.Helper()
+	token, err := signer.SignClaims(jwtv5.MapClaims{
+		"iss":                                    legacyIssuer,
+		"sub":                                    "system:serviceaccount:default:my-sa",
+		"kubernetes.io/serviceaccount/namespace": "default",
+		"kubernetes.io/serviceaccount/secret.name":          "my-sa-token",
+		"kubernetes.io/serviceaccount/service-account.name": "my-sa",
+		"kubernetes.io/serviceaccount/service-account.uid":  legacySAUID,
+	})
+	if err != nil {
+		t.Fatalf("Failed to sign legacy token: %v", err)
+	}
+	return token
+}
+
+// newLegacyTokenSecret returns the Secret holding a legacy token, created age ago.
+func newLegacyTokenSecret(age time.Duration, saUID string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "my-sa-token",
+			Namespace:         "default",
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+			Annotations: map[string]string{
+				"kubernetes.io/service-account.name": "my-sa",
+				"kubernetes.io/service-account.uid":  saUID,
+			},
+		},
+		Type: corev1.SecretTypeServiceAccountToken,
+	}
+}
+
+func TestClassifyToken(t *testing.T) {
+	tests := []struct {
+		name      string
+		claims    jwt.Claims
+		k8sClaims map[string]interface{}
+		expected  TokenKind
+	}{
+		{
+			name: "projected token",
+			claims: jwt.Claims{
+				Issuer: "https://kubernetes.default.svc",
+				Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+			k8sClaims: map[string]interface{}{
+				"kubernetes.io": map[string]interface{}{"namespace": "default"},
+			},
+			expected: ProjectedToken,
+		},
+		{
+			name:     "legacy issuer",
+			claims:   jwt.Claims{Issuer: legacyIssuer},
+			expected: LegacyToken,
+		},
+		{
+			name:   "legacy claims with custom issuer",
+			claims: jwt.Claims{Issuer: "https://kubernetes.default.svc"},
+			k8sClaims: map[string]interface{}{
+				"kubernetes.io/serviceaccount/secret.name": "my-sa-token",
+			},
+			expected: LegacyToken,
+		},
+		{
+			name: "projected token bound to a secret",
+			claims: jwt.Claims{
+				Issuer: "https://kubernetes.default.svc",
+				Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+			k8sClaims: map[string]interface{}{
+				"kubernetes.io": map[string]interface{}{
+					"secret": map[string]interface{}{"name": "my-secret"},
+				},
+			},
+			expected: ProjectedToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyToken(&tt.claims, tt.k8sClaims); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestJWKSValidator_LegacyTokens(t *testing.T) {
+	ctx := context.Background()
+	issuer := "https://kubernetes.default.svc.cluster.local"
+
+	signer, err := testutil.NewJWTSigner(issuer)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		legacyTokens *config.LegacyTokens
+		secret       *corev1.Secret
+		expectError  bool
+		expectLegacy bool
+	}{
+		{
+			name:         "rejected by default",
+			secret:       newLegacyTokenSecret(time.Hour, legacySAUID),
+			expectError:  true,
+			expectLegacy: true,
+		},
+		{
+			name:         "accepted within max age",
+			legacyTokens: &config.LegacyTokens{MaxAge: 24 * time.Hour},
+			secret:       newLegacyTokenSecret(time.Hour, legacySAUID),
+		},
+		{
+			name:         "secret older than max age",
+			legacyTokens: &config.LegacyTokens{MaxAge: 24 * time.Hour},
+			secret:       newLegacyTokenSecret(48*time.Hour, legacySAUID),
+			expectError:  true,
+		},
+		{
+			name:         "secret belongs to another service account",
+			legacyTokens: &config.LegacyTokens{MaxAge: 24 * time.Hour},
+			secret:       newLegacyTokenSecret(time.Hour, "other-uid"),
+			expectError:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.ClustersConfig{
+				Clusters: []config.ClusterConfig{
+					{
+						Name:         "cluster",
+						Issuer:       issuer,
+						Audiences:    []string{"tokensmith"},
+						JWKSData:     createJWKS(signer.PublicKey(), signer.KeyID()),
+						Kubeconfig:   "/etc/tokensmith/cluster.kubeconfig",
+						LegacyTokens: tt.legacyTokens,
+					},
+				},
+			}
+			if err := cfg.Validate(); err != nil {
+				t.Fatalf("Invalid configuration: %v", err)
+			}
+
+			validator := NewJWKSValidator(cfg)
+			validator.SetWorkloadClient("cluster", fake.NewSimpleClientset(tt.secret))
+
+			identity, err := validator.Validate(ctx, signLegacyToken(t, signer))
+			if tt.expectLegacy && !errors.Is(err, ErrLegacyToken) {
+				t.Errorf("Expected ErrLegacyToken, got %v", err)
+			}
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected validation to fail, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validation failed: %v", err)
+			}
+			if identity.Kind != LegacyToken {
+				t.Errorf("Expected kind %q, got %q", LegacyToken, identity.Kind)
+			}
+			if identity.Name != "my-sa" || identity.Namespace != "default" {
+				t.Errorf("Expected default/my-sa, got %s/%s", identity.Namespace, identity.Name)
+			}
+		})
+	}
+
+	t.Run("projected token", func(t *testing.T) {
+		cfg := &config.ClustersConfig{
+			Clusters: []config.ClusterConfig{
+				{
+					Name:      "cluster",
+					Issuer:    issuer,
+					Audiences: []string{"tokensmith"},
+					JWKSData:  createJWKS(signer.PublicKey(), signer.KeyID()),
+				},
+			},
+		}
+		validator := NewJWKSValidator(cfg)
+
+		token, err := signer.GenerateToken("default", "my-sa", legacySAUID,
+			[]string{"tokensmith"}, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+		identity, err := validator.Validate(ctx, token)
+		if err != nil {
+			t.Fatalf("Validation failed: %v", err)
+		}
+		if identity.Kind != ProjectedToken {
+			t.Errorf("Expected kind %q, got %q", ProjectedToken, identity.Kind)
+		}
+	})
+}
+
+func TestValidator_LegacyTokens(t *testing.T) {
+	ctx := context.Background()
+
+	signer, err := testutil.NewJWTSigner("https://kubernetes.default.svc.cluster.local")
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	legacyToken := signLegacyToken(t, signer)
+
+	tests := []struct {
+		name        string
+		maxAge      time.Duration
+		secret      *corev1.Secret
+		expectError bool
+	}{
+		{
+			name:        "rejected by default",
+			secret:      newLegacyTokenSecret(time.Hour, legacySAUID),
+			expectError: true,
+		},
+		{
+			name:   "accepted within max age",
+			maxAge: 24 * time.Hour,
+			secret: newLegacyTokenSecret(time.Hour, legacySAUID),
+		},
+		{
+			name:        "secret older than max age",
+			maxAge:      24 * time.Hour,
+			secret:      newLegacyTokenSecret(48*time.Hour, legacySAUID),
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(tt.secret)
+			client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				tr := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+				tr.Status = authenticationv1.TokenReviewStatus{
+					Authenticated: true,
+					User: authenticationv1.UserInfo{
+						Username: "system:serviceaccount:default:my-sa",
+						UID:      legacySAUID,
+					},
+				}
+				return true, tr, nil
+			})
+
+			validator := NewValidator(client, nil)
+			validator.AllowLegacyTokens(tt.maxAge)
+
+			identity, err := validator.Validate(ctx, legacyToken)
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				if tt.maxAge == 0 && !errors.Is(err, ErrLegacyToken) {
+					t.Errorf("expected ErrLegacyToken, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if identity.Kind != LegacyToken {
+				t.Errorf("expected kind %q, got %q", LegacyToken, identity.Kind)
+			}
+			if identity.Secret == nil || identity.Secret.Name != "my-sa-token" {
+				t.Errorf("expected secret my-sa-token, got %+v", identity.Secret)
+			}
+		})
+	}
+}
+
+func TestCompositeValidator_LegacyTokenReview(t *testing.T) {
+	ctx := context.Background()
+
+	signer, err := testutil.NewJWTSigner("https://kubernetes.default.svc.cluster.local")
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	otherSigner, err := testutil.NewJWTSigner("https://jwks.example.com")
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	legacyToken := signLegacyToken(t, signer)
+
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(unavailable.Close)
+
+	maxAge := &config.LegacyTokens{MaxAge: 24 * time.Hour}
+
+	tests := []struct {
+		name string
+		// jwks configures the first cluster, which accepts legacy tokens
+		jwks config.ClusterConfig
+		// privateLegacyTokens configures legacy tokens of the tokenreview
+		// cluster
+		privateLegacyTokens *config.LegacyTokens
+		// authenticated is the result of every TokenReview
+		authenticated  bool
+		expectError    bool
+		expectCluster  string
+		jwksReviews    int
+		privateReviews int
+	}{
+		{
+			name:                "accepted by tokenreview cluster",
+			jwks:                config.ClusterConfig{JWKSData: createJWKS(otherSigner.PublicKey(), otherSigner.KeyID())},
+			privateLegacyTokens: maxAge,
+			authenticated:       true,
+			expectCluster:       "private",
+			privateReviews:      1,
+		},
+		{
+			name:          "tokenreview cluster without legacy_tokens",
+			jwks:          config.ClusterConfig{JWKSData: createJWKS(otherSigner.PublicKey(), otherSigner.KeyID())},
+			authenticated: true,
+			expectError:   true,
+		},
+		{
+			name:                "key set unavailable",
+			jwks:                config.ClusterConfig{JWKSURI: unavailable.URL},
+			privateLegacyTokens: maxAge,
+			authenticated:       true,
+			expectError:         true,
+		},
+		{
+			name: "jwks-then-tokenreview cross-check accepts",
+			jwks: config.ClusterConfig{
+				JWKSData:   createJWKS(signer.PublicKey(), signer.KeyID()),
+				Validation: config.ValidationJWKSThenTokenReview,
+			},
+			privateLegacyTokens: maxAge,
+			authenticated:       true,
+			expectCluster:       "jwks",
+			jwksReviews:         1,
+		},
+		{
+			name: "jwks-then-tokenreview cross-check rejects",
+			jwks: config.ClusterConfig{
+				JWKSData:   createJWKS(signer.PublicKey(), signer.KeyID()),
+				Validation: config.ValidationJWKSThenTokenReview,
+			},
+			privateLegacyTokens: maxAge,
+			expectError:         true,
+			jwksReviews:         1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jwksCluster := tt.jwks
+			jwksCluster.Name = "jwks"
+			jwksCluster.Issuer = "https://jwks.example.com"
+			jwksCluster.Audiences = []string{"tokensmith"}
+			jwksCluster.LegacyTokens = maxAge
+
+			cfg := &config.ClustersConfig{
+				Clusters: []config.ClusterConfig{
+					jwksCluster,
+					{
+						Name:         "private",
+						Issuer:       "https://kubernetes.default.svc.cluster.local",
+						Audiences:    []string{"tokensmith"},
+						Validation:   config.ValidationTokenReview,
+						LegacyTokens: tt.privateLegacyTokens,
+					},
+				},
+			}
+
+			// newReviewer returns a TokenReview validator counting its reviews
+			newReviewer := func(reviews *int) (*Validator, *fake.Clientset) {
+				client := fake.NewSimpleClientset(newLegacyTokenSecret(time.Hour, legacySAUID))
+				client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					*reviews++
+					tr := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+					tr.Status = authenticationv1.TokenReviewStatus{Authenticated: tt.authenticated}
+					if tt.authenticated {
+						tr.Status.User = authenticationv1.UserInfo{
+							Username: "system:serviceaccount:default:my-sa",
+							UID:      legacySAUID,
+						}
+					}
+					return true, tr, nil
+				})
+				reviewer := NewValidator(client, nil)
+				reviewer.AllowLegacyTokens(maxAge.MaxAge)
+				return reviewer, client
+			}
+
+			var jwksReviews, privateReviews int
+			jwksReviewer, jwksClient := newReviewer(&jwksReviews)
+			privateReviewer, _ := newReviewer(&privateReviews)
+			if tt.privateLegacyTokens == nil {
+				privateReviewer = NewValidator(fake.NewSimpleClientset(), nil)
+			}
+
+			jwksValidator := NewJWKSValidator(cfg)
+			jwksValidator.SetWorkloadClient("jwks", jwksClient)
+			composite := NewCompositeValidator(cfg, jwksValidator)
+			composite.SetTokenReviewValidator("jwks", jwksReviewer)
+			composite.SetTokenReviewValidator("private", privateReviewer)
+
+			identity, err := composite.Validate(ctx, legacyToken)
+			if jwksReviews != tt.jwksReviews {
+				t.Errorf("expected %d reviews by cluster jwks, got %d", tt.jwksReviews, jwksReviews)
+			}
+			if privateReviews != tt.privateReviews {
+				t.Errorf("expected %d reviews by cluster private, got %d", tt.privateReviews, privateReviews)
+			}
+			if tt.expectError {
+				if err == nil {
+					t.Fatal("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if identity.Cluster != tt.expectCluster {
+				t.Errorf("expected cluster %q, got %q", tt.expectCluster, identity.Cluster)
+			}
+			if identity.Kind != LegacyToken {
+				t.Errorf("expected kind %q, got %q", LegacyToken, identity.Kind)
+			}
+		})
+	}
+}
+
+func TestClustersConfig_ValidateLegacyTokenReviewClusters(t *testing.T) {
+	cfg := &config.ClustersConfig{
+		Clusters: []config.ClusterConfig{
+			{
+				Name:         "tenant-a",
+				Issuer:       "https://tenant-a.example.com",
+				Audiences:    []string{"tokensmith"},
+				Validation:   config.ValidationTokenReview,
+				Kubeconfig:   "/etc/tokensmith/tenant-a.kubeconfig",
+				LegacyTokens: &config.LegacyTokens{MaxAge: 24 * time.Hour},
+			},
+			{
+				Name:         "tenant-b",
+				Issuer:       "https://tenant-b.example.com",
+				Audiences:    []string{"tokensmith"},
+				Validation:   config.ValidationTokenReview,
+				Kubeconfig:   "/etc/tokensmith/tenant-b.kubeconfig",
+				LegacyTokens: &config.LegacyTokens{MaxAge: 24 * time.Hour},
+			},
+		},
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for two tokenreview clusters accepting legacy tokens")
+	}
+
+	cfg.Clusters[1].LegacyTokens = nil
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package token
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/holos-run/tokensmith/internal/config"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// mapOIDCIdentity maps the claims of a token from an OIDC issuer to a
+// management cluster service account using the first matching mapping rule.
+// The identity UID combines the issuer and subject, so it's unique across
+// issuers.
+func mapOIDCIdentity(claims *jwt.Claims, allClaims map[string]interface{}, rules []config.MappingRule) (*ServiceAccountIdentity, error) {
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("token has no sub claim")
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+
+		matched, err := matchConditions(allClaims, rule.Conditions)
+		if err != nil {
+			return nil, fmt.Errorf("mapping rule %d: %w", i, err)
+		}
+		if !matched {
+			continue
+		}
+
+		namespace, err := renderMappingTemplate(rule.NamespaceTemplate, allClaims)
+		if err != nil {
+			return nil, fmt.Errorf("mapping rule %d: namespace: %w", i, err)
+		}
+		if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+			return nil, fmt.Errorf("mapping rule %d: invalid namespace %q: %s", i, namespace, strings.Join(errs, ", "))
+		}
+
+		name, err := renderMappingTemplate(rule.NameTemplate, allClaims)
+		if err != nil {
+			return nil, fmt.Errorf("mapping rule %d: name: %w", i, err)
+		}
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return nil, fmt.Errorf("mapping rule %d: invalid name %q: %s", i, name, strings.Join(errs, ", "))
+		}
+
+		return &ServiceAccountIdentity{
+			Namespace: namespace,
+			Name:      name,
+			UID:       claims.Issuer + "#" + claims.Subject,
+			Username:  fmt.Sprintf("system:serviceaccount:%s:%s", namespace, name),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no mapping rule matches the token claims")
+}
+
+// matchConditions reports whether all conditions hold for the claims.
+func matchConditions(allClaims map[string]interface{}, conditions []config.ClaimCondition) (bool, error) {
+	for i := range conditions {
+		cond := &conditions[i]
+		value, ok := allClaims[cond.Claim]
+		if !ok {
+			return false, nil
+		}
+
+		switch {
+		case cond.Equals != "":
+			if fmt.Sprint(value) != cond.Equals {
+				return false, nil
+			}
+		case cond.Matches != "":
+			re, err := cond.Regexp()
+			if err != nil {
+				return false, fmt.Errorf("condition on %s: %w", cond.Claim, err)
+			}
+			if !re.MatchString(fmt.Sprint(value)) {
+				return false, nil
+			}
+		case cond.Contains != "":
+			list, ok := value.([]interface{})
+			if !ok {
+				return false, nil
+			}
+			if !slices.ContainsFunc(list, func(item interface{}) bool {
+				return fmt.Sprint(item) == cond.Contains
+			}) {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// renderMappingTemplate renders the mapping template returned by compiled
+// with data.
+func renderMappingTemplate(compiled func() (*template.Template, error), data any) (string, error) {
+	tmpl, err := compiled()
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,204 @@
+package token
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4/jwt"
+	jwtv5 "github.com/golang-jwt/jwt/v5"
+	"github.com/holos-run/tokensmith/internal/config"
+	"github.com/holos-run/tokensmith/internal/testutil"
+)
+
+const githubIssuer = "https://token.actions.githubusercontent.com"
+
+// githubRules maps GitHub Actions tokens from the main branch to a deployer
+// and tokens from members of the admins group to an admin.
+var githubRules = []config.MappingRule{
+	{
+		Conditions: []config.ClaimCondition{
+			{Claim: "repository", Matches: "holos-run/.*"},
+			{Claim: "ref", Equals: "refs/heads/main"},
+		},
+		Namespace: "ci",
+		Name:      `{{ .repository | replace "/" "-" }}-deployer`,
+	},
+	{
+		Conditions: []config.ClaimCondition{
+			{Claim: "groups", Contains: "admins"},
+		},
+		Namespace: "ci",
+		Name:      `{{ .email | trimSuffix "@example.com" | lower }}`,
+	},
+}
+
+func TestMapOIDCIdentity(t *testing.T) {
+	tests := []struct {
+		name         string
+		claims       map[string]interface{}
+		rules        []config.MappingRule
+		expectError  bool
+		expectedNS   string
+		expectedName string
+	}{
+		{
+			name: "main branch",
+			claims: map[string]interface{}{
+				"repository": "holos-run/tokensmith",
+				"ref":        "refs/heads/main",
+			},
+			rules:        githubRules,
+			expectedNS:   "ci",
+			expectedName: "holos-run-tokensmith-deployer",
+		},
+		{
+			name: "feature branch",
+			claims: map[string]interface{}{
+				"repository": "holos-run/tokensmith",
+				"ref":        "refs/heads/feature",
+			},
+			rules:       githubRules,
+			expectError: true,
+		},
+		{
+			name: "other organization",
+			claims: map[string]interface{}{
+				"repository": "example/holos-run/tokensmith",
+				"ref":        "refs/heads/main",
+			},
+			rules:       githubRules,
+			expectError: true,
+		},
+		{
+			name: "group membership",
+			claims: map[string]interface{}{
+				"email":  "Alice@example.com",
+				"groups": []interface{}{"developers", "admins"},
+			},
+			rules:        githubRules,
+			expectedNS:   "ci",
+			expectedName: "alice",
+		},
+		{
+			name: "rendered name is not a valid service account name",
+			claims: map[string]interface{}{
+				"email": "not valid",
+			},
+			rules: []config.MappingRule{
+				{Namespace: "ci", Name: "{{ .email }}"},
+			},
+			expectError: true,
+		},
+		{
+			name:   "template references missing claim",
+			claims: map[string]interface{}{},
+			rules: []config.MappingRule{
+				{Namespace: "ci", Name: "{{ .email }}"},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := &jwt.Claims{Issuer: githubIssuer, Subject: "repo:holos-run/tokensmith:ref:refs/heads/main"}
+			identity, err := mapOIDCIdentity(claims, tt.claims, tt.rules)
+
+			if tt.expectError {
+				if err == nil {
+					t.Fatalf("Expected error, got identity %+v", identity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if identity.Namespace != tt.expectedNS || identity.Name != tt.expectedName {
+				t.Errorf("Expected %s/%s, got %s/%s", tt.expectedNS, tt.expectedName, identity.Namespace, identity.Name)
+			}
+			if identity.UID != githubIssuer+"#"+claims.Subject {
+				t.Errorf("Expected UID to combine issuer and subject, got %q", identity.UID)
+			}
+		})
+	}
+}
+
+func TestJWKSValidator_OIDCIssuer(t *testing.T) {
+	signer, err := testutil.NewJWTSigner(githubIssuer)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	cfg := &config.ClustersConfig{
+		Clusters: []config.ClusterConfig{
+			{
+				Name:      "github",
+				Type:      config.ClusterTypeOIDC,
+				Issuer:    githubIssuer,
+				Audiences: []string{"tokensmith"},
+				JWKSData:  createJWKS(signer.PublicKey(), signer.KeyID()),
+				Mappings:  githubRules,
+			},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Invalid configuration: %v", err)
+	}
+	validator := NewJWKSValidator(cfg)
+
+	now := time.Now()
+	token, err := signer.SignClaims(jwtv5.MapClaims{
+		"iss":        githubIssuer,
+		"sub":        "repo:holos-run/tokensmith:ref:refs/heads/main",
+		"aud":        []string{"tokensmith"},
+		"exp":        now.Add(time.Hour).Unix(),
+		"iat":        now.Unix(),
+		"repository": "holos-run/tokensmith",
+		"ref":        "refs/heads/main",
+	})
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	identity, err := validator.Validate(context.Background(), token)
+	if err != nil {
+		t.Fatalf("Validation failed: %v", err)
+	}
+	if identity.Namespace != "ci" || identity.Name != "holos-run-tokensmith-deployer" {
+		t.Errorf("Expected ci/holos-run-tokensmith-deployer, got %s/%s", identity.Namespace, identity.Name)
+	}
+	if identity.Kind != OIDCToken {
+		t.Errorf("Expected kind %q, got %q", OIDCToken, identity.Kind)
+	}
+}
+
+func TestMappingRule_CompiledByValidate(t *testing.T) {
+	rule := config.MappingRule{
+		Conditions: []config.ClaimCondition{
+			{Claim: "repository", Matches: "holos-run/.*"},
+		},
+		Namespace: "ci",
+		Name:      "{{ .repository_owner }}-deployer",
+	}
+	if err := rule.Validate(); err != nil {
+		t.Fatalf("Invalid mapping rule: %v", err)
+	}
+
+	// Validate compiles the rule once so validating tokens doesn't recompile it
+	re1, _ := rule.Conditions[0].Regexp()
+	re2, _ := rule.Conditions[0].Regexp()
+	if re1 == nil || re1 != re2 {
+		t.Error("Expected condition regexp to be compiled once by Validate")
+	}
+	ns1, _ := rule.NamespaceTemplate()
+	ns2, _ := rule.NamespaceTemplate()
+	if ns1 == nil || ns1 != ns2 {
+		t.Error("Expected namespace template to be compiled once by Validate")
+	}
+	name1, _ := rule.NameTemplate()
+	name2, _ := rule.NameTemplate()
+	if name1 == nil || name1 != name2 {
+		t.Error("Expected name template to be compiled once by Validate")
+	}
+}
@@ -0,0 +1,129 @@
+package token
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/holos-run/tokensmith/internal/config"
+)
+
+const (
+	// refreshAheadFraction is the fraction of a key set's cache lifetime
+	// after which the background refresher fetches it again.
+	refreshAheadFraction = 0.75
+
+	// minRefreshBackoff and maxRefreshBackoff bound the retry delay after
+	// consecutive refresh failures.
+	minRefreshBackoff = time.Second
+	maxRefreshBackoff = 5 * time.Minute
+
+	// refreshJitter is the maximum relative jitter applied to refresh delays
+	// so refreshers for many clusters don't synchronize.
+	refreshJitter = 0.2
+)
+
+// Start launches a background refresher for each cluster whose keys are
+// fetched over the network. Refreshers fetch key sets and discovery documents
+// ahead of expiry so Validate rarely fetches inline, and retry failures with
+// exponential backoff and jitter. It also launches a watcher for each cluster with a JWKS file,
+// and a service account informer for each cluster with VerifyServiceAccount.
+// Refresh failures are reported through logger.
+// Refreshers and watchers stop when ctx is cancelled.
+func (v *JWKSValidator) Start(ctx context.Context, logger *slog.Logger) {
+	for i := range v.config.Clusters {
+		cluster := &v.config.Clusters[i]
+		switch {
+		case cluster.IsRemote():
+			go v.runRefresher(ctx, cluster, logger)
+		case cluster.JWKSFile != "":
+			go v.watchJWKSFile(ctx, cluster, logger)
+		}
+		if cluster.VerifyServiceAccount {
+			v.watchServiceAccounts(ctx, cluster, logger)
+		}
+	}
+}
+
+// runRefresher periodically refreshes the key set for a single cluster.
+func (v *JWKSValidator) runRefresher(ctx context.Context, cluster *config.ClusterConfig, logger *slog.Logger) {
+	failures := 0
+	for {
+		var delay time.Duration
+		ttl, err := v.refreshCluster(ctx, cluster)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			failures++
+			delay = refreshBackoff(failures)
+			logger.Warn("JWKS refresh failed",
+				slog.String("cluster", cluster.Name),
+				slog.String("issuer", cluster.Issuer),
+				slog.Int("consecutive_failures", failures),
+				slog.Duration("retry_in", delay),
+				slog.String("error", err.Error()),
+			)
+		} else {
+			if failures > 0 {
+				logger.Info("JWKS refresh recovered",
+					slog.String("cluster", cluster.Name),
+					slog.Int("consecutive_failures", failures),
+				)
+			}
+			failures = 0
+			delay = withJitter(time.Duration(float64(ttl) * refreshAheadFraction))
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// refreshCluster fetches the key set for a cluster, and its discovery
+// document if discovery is enabled, and returns the shorter of their cache
+// lifetimes.
+func (v *JWKSValidator) refreshCluster(ctx context.Context, cluster *config.ClusterConfig) (time.Duration, error) {
+	ttl := maxJWKSCacheTTL
+	if cluster.Discovery {
+		doc, err := v.refreshDiscovery(ctx, cluster)
+		if err != nil {
+			return 0, err
+		}
+		ttl = doc.expiresAt.Sub(doc.fetchedAt)
+	}
+
+	uri, err := v.jwksURI(ctx, cluster)
+	if err != nil {
+		return 0, err
+	}
+
+	doc, err := v.refreshDocument(ctx, uri, v.jwksFetcher(cluster, uri))
+	if err != nil {
+		return 0, err
+	}
+
+	return min(ttl, doc.expiresAt.Sub(doc.fetchedAt)), nil
+}
+
+// refreshBackoff returns the jittered retry delay after the given number of
+// consecutive failures.
+func refreshBackoff(failures int) time.Duration {
+	delay := minRefreshBackoff
+	for i := 1; i < failures && delay < maxRefreshBackoff; i++ {
+		delay *= 2
+	}
+	return withJitter(min(delay, maxRefreshBackoff))
+}
+
+// withJitter randomizes d by up to refreshJitter in either direction.
+func withJitter(d time.Duration) time.Duration {
+	factor := 1 + refreshJitter*(2*rand.Float64()-1)
+	return time.Duration(float64(d) * factor)
+}
@@ -0,0 +1,288 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/holos-run/tokensmith/internal/config"
+	"github.com/holos-run/tokensmith/internal/testutil"
+)
+
+func TestRefreshBackoff(t *testing.T) {
+	tests := []struct {
+		failures int
+		base     time.Duration
+	}{
+		{failures: 1, base: time.Second},
+		{failures: 2, base: 2 * time.Second},
+		{failures: 5, base: 16 * time.Second},
+		{failures: 20, base: maxRefreshBackoff},
+	}
+
+	for _, tt := range tests {
+		delay := refreshBackoff(tt.failures)
+		low := time.Duration(float64(tt.base) * (1 - refreshJitter))
+		high := time.Duration(float64(tt.base) * (1 + refreshJitter))
+		if delay < low || delay > high {
+			t.Errorf("failures=%d: expected delay in [%v, %v], got %v", tt.failures, low, high, delay)
+		}
+	}
+}
+
+// newFlakyJWKSServer serves the signer's JWKS until failing is set, after
+// which it responds with HTTP 503.
+func newFlakyJWKSServer(t *testing.T, signer *testutil.JWTSigner, failing *atomic.Bool) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		writeJSON(w, createJWKS(signer.PublicKey(), signer.KeyID()))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestJWKSValidator_ServeStale(t *testing.T) {
+	ctx := context.Background()
+	issuer := "https://cluster.example.com"
+
+	signer, err := testutil.NewJWTSigner(issuer)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	var failing atomic.Bool
+	server := newFlakyJWKSServer(t, signer, &failing)
+
+	cfg := &config.ClustersConfig{
+		Clusters: []config.ClusterConfig{
+			{
+				Name:      "cluster",
+				Issuer:    issuer,
+				Audiences: []string{"tokensmith"},
+				JWKSURI:   server.URL,
+				MaxStale:  time.Hour,
+			},
+		},
+	}
+	validator := NewJWKSValidator(cfg)
+
+	token, err := signer.GenerateToken("default", "my-sa", uuid.New().String(),
+		[]string{"tokensmith"}, time.Now().Add(1*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	// Populate the cache, then take the issuer down
+	if _, err := validator.Validate(ctx, token); err != nil {
+		t.Fatalf("Validation failed: %v", err)
+	}
+	failing.Store(true)
+
+	expire := func(age time.Duration) {
+		validator.mu.Lock()
+		defer validator.mu.Unlock()
+		validator.cache[server.URL].expiresAt = time.Now().Add(-age)
+	}
+
+	t.Run("serve stale key set within max-stale", func(t *testing.T) {
+		expire(time.Minute)
+		if _, err := validator.Validate(ctx, token); err != nil {
+			t.Fatalf("Expected stale key set to be served: %v", err)
+		}
+	})
+
+	t.Run("fail beyond max-stale", func(t *testing.T) {
+		expire(2 * time.Hour)
+		if _, err := validator.Validate(ctx, token); err == nil {
+			t.Fatal("Expected validation to fail beyond max-stale window")
+		}
+	})
+}
+
+func TestJWKSValidator_ServeStaleHangingIssuer(t *testing.T) {
+	ctx := context.Background()
+	issuer := "https://cluster.example.com"
+
+	signer, err := testutil.NewJWTSigner(issuer)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	// The issuer hangs once hanging is set, until the test ends
+	var hanging atomic.Bool
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if hanging.Load() {
+			<-release
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		writeJSON(w, createJWKS(signer.PublicKey(), signer.KeyID()))
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	cfg := &config.ClustersConfig{
+		Clusters: []config.ClusterConfig{
+			{
+				Name:      "cluster",
+				Issuer:    issuer,
+				Audiences: []string{"tokensmith"},
+				JWKSURI:   server.URL,
+				MaxStale:  time.Hour,
+			},
+		},
+	}
+	validator := NewJWKSValidator(cfg)
+
+	token, err := signer.GenerateToken("default", "my-sa", uuid.New().String(),
+		[]string{"tokensmith"}, time.Now().Add(1*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	if _, err := validator.Validate(ctx, token); err != nil {
+		t.Fatalf("Validation failed: %v", err)
+	}
+
+	hanging.Store(true)
+	validator.mu.Lock()
+	validator.cache[server.URL].expiresAt = time.Now().Add(-time.Minute)
+	validator.mu.Unlock()
+
+	for range 5 {
+		start := time.Now()
+		if _, err := validator.Validate(ctx, token); err != nil {
+			t.Fatalf("Expected stale key set to be served: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Fatalf("Expected stale key set to be served without waiting for the issuer, took %v", elapsed)
+		}
+	}
+
+	// Wait for the background refresh to reach the issuer
+	deadline := time.Now().Add(5 * time.Second)
+	for fetches.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected a background refresh")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("Expected a single background refresh, got %d fetches in total", got)
+	}
+}
+
+func TestJWKSValidator_InlineFetchBackoff(t *testing.T) {
+	ctx := context.Background()
+	issuer := "https://cluster.example.com"
+
+	signer, err := testutil.NewJWTSigner(issuer)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	// The issuer is down and nothing is cached
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := &config.ClustersConfig{
+		Clusters: []config.ClusterConfig{
+			{
+				Name:      "cluster",
+				Issuer:    issuer,
+				Audiences: []string{"tokensmith"},
+				JWKSURI:   server.URL,
+			},
+		},
+	}
+	validator := NewJWKSValidator(cfg)
+
+	token, err := signer.GenerateToken("default", "my-sa", uuid.New().String(),
+		[]string{"tokensmith"}, time.Now().Add(1*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	for range 5 {
+		if _, err := validator.Validate(ctx, token); !errors.Is(err, ErrKeysUnavailable) {
+			t.Fatalf("Expected ErrKeysUnavailable, got %v", err)
+		}
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("Expected requests to return the last error while the fetch backs off, got %d fetches", got)
+	}
+
+	// The next request fetches again once the backoff has elapsed
+	validator.mu.Lock()
+	validator.fetchFailures[server.URL].retryAt = time.Now().Add(-time.Second)
+	validator.mu.Unlock()
+
+	if _, err := validator.Validate(ctx, token); !errors.Is(err, ErrKeysUnavailable) {
+		t.Fatalf("Expected ErrKeysUnavailable, got %v", err)
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("Expected a fetch after the backoff elapsed, got %d fetches", got)
+	}
+}
+
+func TestJWKSValidator_Start(t *testing.T) {
+	issuer := "https://cluster.example.com"
+
+	signer, err := testutil.NewJWTSigner(issuer)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	var failing atomic.Bool
+	server := newFlakyJWKSServer(t, signer, &failing)
+
+	cfg := &config.ClustersConfig{
+		Clusters: []config.ClusterConfig{
+			{
+				Name:      "cluster",
+				Issuer:    issuer,
+				Audiences: []string{"tokensmith"},
+				JWKSURI:   server.URL,
+			},
+		},
+	}
+	validator := NewJWKSValidator(cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	validator.Start(ctx, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	// The refresher fetches the key set immediately
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		validator.mu.RLock()
+		_, ok := validator.cache[server.URL]
+		validator.mu.RUnlock()
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected background refresher to populate the cache")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,93 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// replaySweepInterval is how often expired entries are removed from the
+// replay tracker.
+const replaySweepInterval = time.Minute
+
+// ErrTokenReplayed is returned when a token is presented from a different
+// source than it was first seen from and replay protection denies it.
+var ErrTokenReplayed = errors.New("token replayed from a different source")
+
+// Source identifies where a token was presented from.
+type Source struct {
+	// Address is the IP address of the downstream peer.
+	Address string
+
+	// Principal is the SPIFFE ID of the downstream peer's certificate, if any.
+	Principal string
+}
+
+// String returns the source in a form suitable for log and error messages.
+func (s Source) String() string {
+	return fmt.Sprintf("address=%q principal=%q", s.Address, s.Principal)
+}
+
+type sourceContextKey struct{}
+
+// WithSource returns a copy of ctx carrying the source a token was presented
+// from, for use by replay protection.
+func WithSource(ctx context.Context, source Source) context.Context {
+	return context.WithValue(ctx, sourceContextKey{}, source)
+}
+
+// SourceFromContext returns the source stored in ctx by WithSource.
+func SourceFromContext(ctx context.Context) (Source, bool) {
+	source, ok := ctx.Value(sourceContextKey{}).(Source)
+	return source, ok
+}
+
+// seenToken records where a token was first seen.
+type seenToken struct {
+	source    Source
+	expiresAt time.Time
+}
+
+// replayTracker records the source each token ID was first seen from until
+// the token expires, which bounds its memory to the set of live tokens.
+type replayTracker struct {
+	mu        sync.Mutex
+	seen      map[string]*seenToken
+	nextSweep time.Time
+}
+
+// newReplayTracker creates an empty replay tracker.
+func newReplayTracker() *replayTracker {
+	return &replayTracker{
+		seen: make(map[string]*seenToken),
+	}
+}
+
+// observe records that the token with key was presented from source at now.
+// It returns the source the token was first seen from and whether that
+// differs from source.
+func (r *replayTracker) observe(key string, source Source, expiresAt, now time.Time) (Source, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now.After(r.nextSweep) {
+		for k, entry := range r.seen {
+			if now.After(entry.expiresAt) {
+				delete(r.seen, k)
+			}
+		}
+		r.nextSweep = now.Add(replaySweepInterval)
+	}
+
+	if entry, ok := r.seen[key]; ok && !now.After(entry.expiresAt) {
+		return entry.source, entry.source != source
+	}
+
+	r.seen[key] = &seenToken{
+		source:    source,
+		expiresAt: expiresAt,
+	}
+	return source, false
+}
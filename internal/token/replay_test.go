@@ -0,0 +1,123 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/holos-run/tokensmith/internal/config"
+	"github.com/holos-run/tokensmith/internal/testutil"
+)
+
+func TestReplayTracker(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := now.Add(time.Hour)
+	podA := Source{Address: "10.0.0.1", Principal: "spiffe://cluster.local/ns/default/sa/my-sa"}
+	podB := Source{Address: "10.0.0.2", Principal: "spiffe://cluster.local/ns/default/sa/my-sa"}
+
+	tracker := newReplayTracker()
+
+	if _, replayed := tracker.observe("cluster/jti-1", podA, expiresAt, now); replayed {
+		t.Error("Expected first use not to be a replay")
+	}
+	if _, replayed := tracker.observe("cluster/jti-1", podA, expiresAt, now.Add(time.Minute)); replayed {
+		t.Error("Expected reuse from the same source not to be a replay")
+	}
+
+	first, replayed := tracker.observe("cluster/jti-1", podB, expiresAt, now.Add(2*time.Minute))
+	if !replayed {
+		t.Error("Expected use from a different source to be a replay")
+	}
+	if first != podA {
+		t.Errorf("Expected first source %v, got %v", podA, first)
+	}
+
+	if _, replayed := tracker.observe("cluster/jti-2", podB, expiresAt, now); replayed {
+		t.Error("Expected a different token ID not to be a replay")
+	}
+
+	// Entries are removed once their token expires
+	tracker.observe("cluster/jti-3", podA, now.Add(3*time.Hour), now.Add(2*time.Hour))
+	tracker.mu.Lock()
+	remaining := len(tracker.seen)
+	tracker.mu.Unlock()
+	if remaining != 1 {
+		t.Errorf("Expected expired entries to be swept, %d entries remain", remaining)
+	}
+}
+
+func TestJWKSValidator_ReplayProtection(t *testing.T) {
+	issuer := "https://kubernetes.default.svc.cluster.local"
+	signer, err := testutil.NewJWTSigner(issuer)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	podA := WithSource(context.Background(), Source{Address: "10.0.0.1"})
+	podB := WithSource(context.Background(), Source{Address: "10.0.0.2"})
+
+	tests := []struct {
+		name   string
+		action string
+	}{
+		{name: "flag", action: config.ReplayActionFlag},
+		{name: "deny", action: config.ReplayActionDeny},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.ClustersConfig{
+				Clusters: []config.ClusterConfig{
+					{
+						Name:      "cluster",
+						Issuer:    issuer,
+						Audiences: []string{"tokensmith"},
+						JWKSData:  createJWKS(signer.PublicKey(), signer.KeyID()),
+						ReplayProtection: &config.ReplayProtection{
+							Action: tt.action,
+						},
+					},
+				},
+			}
+			if err := cfg.Validate(); err != nil {
+				t.Fatalf("Invalid configuration: %v", err)
+			}
+			validator := NewJWKSValidator(cfg)
+
+			token, err := signer.GenerateToken("default", "my-sa", uuid.New().String(),
+				[]string{"tokensmith"}, time.Now().Add(1*time.Hour))
+			if err != nil {
+				t.Fatalf("Failed to generate token: %v", err)
+			}
+
+			for range 2 {
+				identity, err := validator.Validate(podA, token)
+				if err != nil {
+					t.Fatalf("Validation failed: %v", err)
+				}
+				if identity.ReplaySource != nil {
+					t.Errorf("Expected no replay from the same source, got %v", identity.ReplaySource)
+				}
+				if identity.TokenID == "" {
+					t.Error("Expected token ID to be set")
+				}
+			}
+
+			identity, err := validator.Validate(podB, token)
+			if tt.action == config.ReplayActionDeny {
+				if !errors.Is(err, ErrTokenReplayed) {
+					t.Errorf("Expected ErrTokenReplayed, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validation failed: %v", err)
+			}
+			if identity.ReplaySource == nil || identity.ReplaySource.Address != "10.0.0.1" {
+				t.Errorf("Expected replay flagged with first source 10.0.0.1, got %v", identity.ReplaySource)
+			}
+		})
+	}
+}
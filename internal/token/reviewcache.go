@@ -0,0 +1,92 @@
+package token
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// reviewCacheSweepInterval is how often expired entries are removed from the
+// TokenReview result cache.
+const reviewCacheSweepInterval = time.Minute
+
+// cachedReview is a TokenReview validation result.
+type cachedReview struct {
+	identity  *ServiceAccountIdentity
+	err       error
+	expiresAt time.Time
+}
+
+// reviewCache caches TokenReview validation results keyed by the SHA-256 hash
+// of the token, so tokens are never held in memory by the cache.
+type reviewCache struct {
+	mu          sync.Mutex
+	entries     map[[sha256.Size]byte]*cachedReview
+	maxTTL      time.Duration
+	negativeTTL time.Duration
+	nextSweep   time.Time
+}
+
+// newReviewCache creates a cache holding successful results for up to maxTTL
+// and failed results for negativeTTL.
+func newReviewCache(maxTTL, negativeTTL time.Duration) *reviewCache {
+	return &reviewCache{
+		entries:     make(map[[sha256.Size]byte]*cachedReview),
+		maxTTL:      maxTTL,
+		negativeTTL: negativeTTL,
+	}
+}
+
+// get returns the cached result for a token at now, if any.
+func (c *reviewCache) get(bearerToken string, now time.Time) (*cachedReview, bool) {
+	key := sha256.Sum256([]byte(bearerToken))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || now.After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry, true
+}
+
+// set caches the result of validating a token at now. Successful results are
+// cached until the token expires, capped at maxTTL; expiry is nil for tokens
+// without an exp claim. Failed results are cached for negativeTTL.
+func (c *reviewCache) set(bearerToken string, identity *ServiceAccountIdentity, err error, expiry *time.Time, now time.Time) {
+	ttl := c.maxTTL
+	if err != nil {
+		ttl = c.negativeTTL
+	} else if expiry != nil {
+		ttl = min(ttl, expiry.Sub(now))
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	entry := &cachedReview{
+		err:       err,
+		expiresAt: now.Add(ttl),
+	}
+	if identity != nil {
+		cached := *identity
+		entry.identity = &cached
+	}
+
+	key := sha256.Sum256([]byte(bearerToken))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.After(c.nextSweep) {
+		for k, e := range c.entries {
+			if now.After(e.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		c.nextSweep = now.Add(reviewCacheSweepInterval)
+	}
+
+	c.entries[key] = entry
+}
@@ -0,0 +1,76 @@
+package token
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReviewCache(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	identity := &ServiceAccountIdentity{Namespace: "default", Name: "my-sa"}
+	soon := now.Add(10 * time.Second)
+	later := now.Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		identity *ServiceAccountIdentity
+		err      error
+		expiry   *time.Time
+		wantTTL  time.Duration
+	}{
+		{
+			name:     "capped at max TTL",
+			identity: identity,
+			expiry:   &later,
+			wantTTL:  30 * time.Second,
+		},
+		{
+			name:     "capped at token expiry",
+			identity: identity,
+			expiry:   &soon,
+			wantTTL:  10 * time.Second,
+		},
+		{
+			name:     "token without expiry",
+			identity: identity,
+			wantTTL:  30 * time.Second,
+		},
+		{
+			name:    "negative result",
+			err:     errors.New("token is not authenticated"),
+			expiry:  &later,
+			wantTTL: 5 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := newReviewCache(30*time.Second, 5*time.Second)
+			cache.set("token", tt.identity, tt.err, tt.expiry, now)
+
+			cached, ok := cache.get("token", now.Add(tt.wantTTL))
+			if !ok {
+				t.Fatalf("Expected result to be cached for %s", tt.wantTTL)
+			}
+			if cached.err != tt.err {
+				t.Errorf("Expected error %v, got %v", tt.err, cached.err)
+			}
+			if _, ok := cache.get("token", now.Add(tt.wantTTL+time.Second)); ok {
+				t.Errorf("Expected result to expire after %s", tt.wantTTL)
+			}
+			if _, ok := cache.get("other", now); ok {
+				t.Error("Expected no result for a different token")
+			}
+		})
+	}
+
+	t.Run("expired token is not cached", func(t *testing.T) {
+		cache := newReviewCache(30*time.Second, 5*time.Second)
+		expired := now.Add(-time.Second)
+		cache.set("token", identity, nil, &expired, now)
+		if _, ok := cache.get("token", now); ok {
+			t.Error("Expected expired token not to be cached")
+		}
+	})
+}
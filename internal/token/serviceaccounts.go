@@ -0,0 +1,162 @@
+package token
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/holos-run/tokensmith/internal/config"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// serviceAccountResync is how often service account informers replay their
+// cache to event handlers.
+const serviceAccountResync = 10 * time.Minute
+
+// serviceAccountWatcher holds an informer cache of the service accounts in a
+// workload cluster.
+type serviceAccountWatcher struct {
+	informer cache.SharedIndexInformer
+	lister   corelisters.ServiceAccountLister
+}
+
+// OnServiceAccountRemoved registers fn to be called with the cluster name and
+// UID of each service account that is deleted or recreated in a cluster with
+// VerifyServiceAccount enabled, from the time it is registered.
+func (v *JWKSValidator) OnServiceAccountRemoved(fn func(cluster, uid string)) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.serviceAccountHandlers = append(v.serviceAccountHandlers, fn)
+}
+
+// WaitForServiceAccounts blocks until the service account informers started
+// by Start have synced, or ctx is done. It returns the sorted names of the
+// clusters whose service accounts have not synced. Their informers keep
+// retrying in the background, and tokens from those clusters are rejected
+// until they sync.
+func (v *JWKSValidator) WaitForServiceAccounts(ctx context.Context) []string {
+	v.mu.RLock()
+	watchers := make(map[string]*serviceAccountWatcher, len(v.serviceAccounts))
+	for name, w := range v.serviceAccounts {
+		watchers[name] = w
+	}
+	v.mu.RUnlock()
+
+	var unsynced []string
+	for name, w := range watchers {
+		if !cache.WaitForCacheSync(ctx.Done(), w.informer.HasSynced) {
+			unsynced = append(unsynced, name)
+		}
+	}
+	slices.Sort(unsynced)
+	return unsynced
+}
+
+// watchServiceAccounts starts a service account informer for a cluster using
+// its workload client. The informer stops when ctx is cancelled.
+func (v *JWKSValidator) watchServiceAccounts(ctx context.Context, cluster *config.ClusterConfig, logger *slog.Logger) {
+	v.mu.RLock()
+	client, ok := v.workloadClients[cluster.Name]
+	v.mu.RUnlock()
+	if !ok {
+		logger.Error("no workload client configured for service account verification",
+			slog.String("cluster", cluster.Name),
+		)
+		return
+	}
+
+	factory := informers.NewSharedInformerFactory(client, serviceAccountResync)
+	serviceAccounts := factory.Core().V1().ServiceAccounts()
+	informer := serviceAccounts.Informer()
+
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldSA, ok := oldObj.(*corev1.ServiceAccount)
+			if !ok {
+				return
+			}
+			newSA, ok := newObj.(*corev1.ServiceAccount)
+			if !ok {
+				return
+			}
+			// A missed delete shows up as an update with a new UID
+			if oldSA.UID != newSA.UID {
+				v.serviceAccountRemoved(cluster.Name, oldSA, logger)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if sa, ok := obj.(*corev1.ServiceAccount); ok {
+				v.serviceAccountRemoved(cluster.Name, sa, logger)
+			}
+		},
+	})
+	if err != nil {
+		logger.Error("failed to watch service accounts",
+			slog.String("cluster", cluster.Name),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	v.mu.Lock()
+	v.serviceAccounts[cluster.Name] = &serviceAccountWatcher{
+		informer: informer,
+		lister:   serviceAccounts.Lister(),
+	}
+	v.mu.Unlock()
+
+	factory.Start(ctx.Done())
+}
+
+// serviceAccountRemoved notifies the registered handlers that a service
+// account no longer exists with its UID.
+func (v *JWKSValidator) serviceAccountRemoved(cluster string, sa *corev1.ServiceAccount, logger *slog.Logger) {
+	logger.Info("service account removed, evicting cached tokens",
+		slog.String("cluster", cluster),
+		slog.String("namespace", sa.Namespace),
+		slog.String("service_account", sa.Name),
+		slog.String("uid", string(sa.UID)),
+	)
+
+	v.mu.RLock()
+	handlers := v.serviceAccountHandlers
+	v.mu.RUnlock()
+
+	for _, fn := range handlers {
+		fn(cluster, string(sa.UID))
+	}
+}
+
+// verifyServiceAccount checks that the service account of an identity still
+// exists in the workload cluster with the same UID.
+func (v *JWKSValidator) verifyServiceAccount(cluster string, identity *ServiceAccountIdentity) error {
+	v.mu.RLock()
+	w, ok := v.serviceAccounts[cluster]
+	v.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("no service account watcher started for cluster %s", cluster)
+	}
+	if !w.informer.HasSynced() {
+		return fmt.Errorf("service accounts of cluster %s have not synced", cluster)
+	}
+
+	sa, err := w.lister.ServiceAccounts(identity.Namespace).Get(identity.Name)
+	if err != nil {
+		return fmt.Errorf("service account %s/%s not found: %w", identity.Namespace, identity.Name, err)
+	}
+
+	if string(sa.UID) != identity.UID {
+		return fmt.Errorf("service account %s/%s has been recreated: uid %s does not match %s",
+			identity.Namespace, identity.Name, sa.UID, identity.UID)
+	}
+
+	return nil
+}
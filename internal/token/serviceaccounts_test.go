@@ -0,0 +1,187 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/holos-run/tokensmith/internal/config"
+	"github.com/holos-run/tokensmith/internal/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestJWKSValidator_VerifyServiceAccount(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	issuer := "https://cluster.example.com"
+
+	signer, err := testutil.NewJWTSigner(issuer)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	cfg := &config.ClustersConfig{
+		Clusters: []config.ClusterConfig{
+			{
+				Name:                 "cluster",
+				Issuer:               issuer,
+				Audiences:            []string{"tokensmith"},
+				JWKSData:             createJWKS(signer.PublicKey(), signer.KeyID()),
+				Kubeconfig:           "/etc/tokensmith/cluster.kubeconfig",
+				VerifyServiceAccount: true,
+			},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Invalid configuration: %v", err)
+	}
+
+	liveUID := uuid.New().String()
+	workloadClient := fake.NewSimpleClientset(&corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "my-sa",
+			Namespace: "default",
+			UID:       types.UID(liveUID),
+		},
+	})
+
+	validator := NewJWKSValidator(cfg)
+	validator.SetWorkloadClient("cluster", workloadClient)
+
+	removed := make(chan string, 1)
+	validator.OnServiceAccountRemoved(func(cluster, uid string) {
+		removed <- cluster + "/" + uid
+	})
+
+	validator.Start(ctx, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	syncCtx, syncCancel := context.WithTimeout(ctx, 10*time.Second)
+	defer syncCancel()
+	if unsynced := validator.WaitForServiceAccounts(syncCtx); len(unsynced) > 0 {
+		t.Fatalf("Service accounts did not sync: %v", unsynced)
+	}
+
+	validate := func(name, uid string) error {
+		token, err := signer.GenerateToken("default", name, uid, []string{"tokensmith"}, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+		_, err = validator.Validate(ctx, token)
+		return err
+	}
+
+	if err := validate("my-sa", liveUID); err != nil {
+		t.Fatalf("Expected token for live service account to be valid: %v", err)
+	}
+	if err := validate("my-sa", uuid.New().String()); err == nil {
+		t.Error("Expected token for recreated service account to be rejected")
+	}
+	if err := validate("other-sa", uuid.New().String()); err == nil {
+		t.Error("Expected token for missing service account to be rejected")
+	}
+
+	// Deleting the service account notifies handlers and invalidates its tokens
+	err = workloadClient.CoreV1().ServiceAccounts("default").Delete(ctx, "my-sa", metav1.DeleteOptions{})
+	if err != nil {
+		t.Fatalf("Failed to delete service account: %v", err)
+	}
+	select {
+	case got := <-removed:
+		if got != "cluster/"+liveUID {
+			t.Errorf("Expected removal of cluster/%s, got %s", liveUID, got)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected handler to be called for deleted service account")
+	}
+	if err := validate("my-sa", liveUID); err == nil {
+		t.Error("Expected token for deleted service account to be rejected")
+	}
+}
+
+func TestJWKSValidator_VerifyServiceAccountNotStarted(t *testing.T) {
+	signer, err := testutil.NewJWTSigner("https://cluster.example.com")
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	validator := NewJWKSValidator(&config.ClustersConfig{
+		Clusters: []config.ClusterConfig{
+			{
+				Name:                 "cluster",
+				Issuer:               "https://cluster.example.com",
+				Audiences:            []string{"tokensmith"},
+				JWKSData:             createJWKS(signer.PublicKey(), signer.KeyID()),
+				Kubeconfig:           "/etc/tokensmith/cluster.kubeconfig",
+				VerifyServiceAccount: true,
+			},
+		},
+	})
+
+	token, err := signer.GenerateToken("default", "my-sa", uuid.New().String(), []string{"tokensmith"}, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	if _, err := validator.Validate(context.Background(), token); err == nil {
+		t.Error("Expected validation to fail without a service account watcher")
+	}
+}
+
+func TestJWKSValidator_VerifyServiceAccountNotSynced(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	issuer := "https://cluster.example.com"
+
+	signer, err := testutil.NewJWTSigner(issuer)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	cfg := &config.ClustersConfig{
+		Clusters: []config.ClusterConfig{
+			{
+				Name:                 "cluster",
+				Issuer:               issuer,
+				Audiences:            []string{"tokensmith"},
+				JWKSData:             createJWKS(signer.PublicKey(), signer.KeyID()),
+				Kubeconfig:           "/etc/tokensmith/cluster.kubeconfig",
+				VerifyServiceAccount: true,
+			},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Invalid configuration: %v", err)
+	}
+
+	// The workload API server is unreachable, so the informer never syncs
+	workloadClient := fake.NewSimpleClientset()
+	workloadClient.PrependReactor("list", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("connection refused")
+	})
+
+	validator := NewJWKSValidator(cfg)
+	validator.SetWorkloadClient("cluster", workloadClient)
+	validator.Start(ctx, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	syncCtx, syncCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer syncCancel()
+	unsynced := validator.WaitForServiceAccounts(syncCtx)
+	if len(unsynced) != 1 || unsynced[0] != "cluster" {
+		t.Fatalf("Expected cluster to be reported as not synced, got %v", unsynced)
+	}
+
+	token, err := signer.GenerateToken("default", "my-sa", uuid.New().String(), []string{"tokensmith"}, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	if _, err := validator.Validate(ctx, token); err == nil {
+		t.Error("Expected validation to fail closed before service accounts sync")
+	}
+}
@@ -0,0 +1,110 @@
+package token
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/holos-run/tokensmith/internal/config"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// spiffeScheme is the URI scheme of SPIFFE IDs.
+const spiffeScheme = "spiffe://"
+
+// parseSPIFFEID splits a SPIFFE ID into its trust domain and path.
+func parseSPIFFEID(id string) (trustDomain, path string, err error) {
+	rest, ok := strings.CutPrefix(id, spiffeScheme)
+	if !ok {
+		return "", "", fmt.Errorf("%q is not a SPIFFE ID", id)
+	}
+	if strings.ContainsAny(rest, "?#") {
+		return "", "", fmt.Errorf("SPIFFE ID %q must not contain a query or fragment", id)
+	}
+
+	trustDomain, path, _ = strings.Cut(rest, "/")
+	if err := config.ValidateTrustDomain(trustDomain); err != nil {
+		return "", "", fmt.Errorf("invalid SPIFFE ID %q: %w", id, err)
+	}
+	return trustDomain, "/" + path, nil
+}
+
+// findCluster returns the cluster configuration for the unverified claims of
+// a token. JWT-SVIDs are matched by the trust domain of their SPIFFE ID and
+// other tokens by issuer.
+func findCluster(cfg *config.ClustersConfig, claims *jwt.Claims) (*config.ClusterConfig, error) {
+	if trustDomain, _, err := parseSPIFFEID(claims.Subject); err == nil {
+		if cluster := cfg.FindByTrustDomain(trustDomain); cluster != nil {
+			if cluster.Issuer != "" && cluster.Issuer != claims.Issuer {
+				return nil, fmt.Errorf("issuer %q does not match trust domain %s", claims.Issuer, trustDomain)
+			}
+			return cluster, nil
+		}
+	}
+
+	cluster := cfg.FindByIssuer(claims.Issuer)
+	if cluster == nil {
+		return nil, fmt.Errorf("unknown issuer: %s", claims.Issuer)
+	}
+	return cluster, nil
+}
+
+// extractSPIFFEIdentity maps the SPIFFE ID in the subject of a JWT-SVID to a
+// service account using the first path template that matches it. The
+// identity UID is the SPIFFE ID.
+func extractSPIFFEIdentity(claims *jwt.Claims, cluster *config.ClusterConfig) (*ServiceAccountIdentity, error) {
+	trustDomain, path, err := parseSPIFFEID(claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if trustDomain != cluster.TrustDomain {
+		return nil, fmt.Errorf("SPIFFE ID %q is not in trust domain %s", claims.Subject, cluster.TrustDomain)
+	}
+
+	for _, template := range cluster.GetSPIFFEPaths() {
+		namespace, name, ok := matchSPIFFEPath(template, path)
+		if !ok {
+			continue
+		}
+
+		if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid namespace %q in SPIFFE ID: %s", namespace, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid name %q in SPIFFE ID: %s", name, strings.Join(errs, ", "))
+		}
+
+		return &ServiceAccountIdentity{
+			Namespace: namespace,
+			Name:      name,
+			UID:       claims.Subject,
+			Username:  fmt.Sprintf("system:serviceaccount:%s:%s", namespace, name),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("SPIFFE ID %q matches no path template", claims.Subject)
+}
+
+// matchSPIFFEPath matches a SPIFFE ID path against a path template and returns
+// the captured namespace and name.
+func matchSPIFFEPath(template, path string) (namespace, name string, ok bool) {
+	want := strings.Split(template, "/")
+	got := strings.Split(path, "/")
+	if len(want) != len(got) {
+		return "", "", false
+	}
+
+	for i, segment := range want {
+		switch segment {
+		case config.SPIFFENamespacePlaceholder:
+			namespace = got[i]
+		case config.SPIFFENamePlaceholder:
+			name = got[i]
+		default:
+			if got[i] != segment {
+				return "", "", false
+			}
+		}
+	}
+	return namespace, name, true
+}
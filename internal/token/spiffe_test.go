@@ -0,0 +1,358 @@
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	jwtv5 "github.com/golang-jwt/jwt/v5"
+	"github.com/holos-run/tokensmith/internal/config"
+	"github.com/holos-run/tokensmith/internal/testutil"
+)
+
+// trustBundle is a SPIFFE trust bundle document.
+type trustBundle struct {
+	Keys        []jose.JSONWebKey `json:"keys"`
+	Sequence    int               `json:"spiffe_sequence,omitempty"`
+	RefreshHint int               `json:"spiffe_refresh_hint,omitempty"`
+}
+
+// newTrustBundle returns a trust bundle holding the public key of signer as
+// a JWT-SVID key.
+func newTrustBundle(signer *testutil.JWTSigner) *trustBundle {
+	key := rsaPublicKeyToJWK(signer.PublicKey(), signer.KeyID())
+	key.Use = "jwt-svid"
+	return &trustBundle{Keys: []jose.JSONWebKey{key}, Sequence: 1}
+}
+
+func TestExtractSPIFFEIdentity(t *testing.T) {
+	tests := []struct {
+		name         string
+		subject      string
+		paths        []string
+		expectError  bool
+		expectedNS   string
+		expectedName string
+	}{
+		{
+			name:         "istio style",
+			subject:      "spiffe://cluster.local/ns/default/sa/web",
+			expectedNS:   "default",
+			expectedName: "web",
+		},
+		{
+			name:         "custom template",
+			subject:      "spiffe://cluster.local/k8s/prod/workload/api",
+			paths:        []string{"/ns/{namespace}/sa/{name}", "/k8s/{namespace}/workload/{name}"},
+			expectedNS:   "prod",
+			expectedName: "api",
+		},
+		{
+			name:        "no template matches",
+			subject:     "spiffe://cluster.local/ns/default/sa/web/extra",
+			expectError: true,
+		},
+		{
+			name:        "other trust domain",
+			subject:     "spiffe://example.org/ns/default/sa/web",
+			expectError: true,
+		},
+		{
+			name:        "invalid namespace",
+			subject:     "spiffe://cluster.local/ns/Default/sa/web",
+			expectError: true,
+		},
+		{
+			name:        "empty namespace",
+			subject:     "spiffe://cluster.local/ns//sa/web",
+			expectError: true,
+		},
+		{
+			name:        "not a SPIFFE ID",
+			subject:     "system:serviceaccount:default:web",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &config.ClusterConfig{TrustDomain: "cluster.local", SPIFFEPaths: tt.paths}
+			identity, err := extractSPIFFEIdentity(&jwt.Claims{Subject: tt.subject}, cluster)
+
+			if tt.expectError {
+				if err == nil {
+					t.Fatalf("Expected error, got identity %+v", identity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if identity.Namespace != tt.expectedNS || identity.Name != tt.expectedName {
+				t.Errorf("Expected %s/%s, got %s/%s", tt.expectedNS, tt.expectedName, identity.Namespace, identity.Name)
+			}
+			if identity.UID != tt.subject {
+				t.Errorf("Expected UID %q, got %q", tt.subject, identity.UID)
+			}
+		})
+	}
+}
+
+func TestJWKSValidator_SPIFFE(t *testing.T) {
+	ctx := context.Background()
+
+	signer, err := testutil.NewJWTSigner("")
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	data, err := json.Marshal(newTrustBundle(signer))
+	if err != nil {
+		t.Fatalf("Failed to marshal trust bundle: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "bundle.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("Failed to write trust bundle: %v", err)
+	}
+
+	x509Bundle := newTrustBundle(signer)
+	x509Bundle.Keys[0].Use = "x509-svid"
+	x509Keys := &jose.JSONWebKeySet{Keys: x509Bundle.Keys}
+
+	cfg := &config.ClustersConfig{
+		Clusters: []config.ClusterConfig{
+			{
+				Name:        "mesh",
+				Type:        config.ClusterTypeSPIFFE,
+				TrustDomain: "cluster.local",
+				Audiences:   []string{"tokensmith"},
+				JWKSFile:    path,
+			},
+			{
+				Name:        "x509-only",
+				Type:        config.ClusterTypeSPIFFE,
+				TrustDomain: "example.org",
+				Audiences:   []string{"tokensmith"},
+				JWKSData:    x509Keys,
+			},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Invalid configuration: %v", err)
+	}
+	validator := NewJWKSValidator(cfg)
+
+	now := time.Now()
+	tests := []struct {
+		name        string
+		claims      jwtv5.MapClaims
+		expectError bool
+	}{
+		{
+			name: "valid JWT-SVID",
+			claims: jwtv5.MapClaims{
+				"sub": "spiffe://cluster.local/ns/default/sa/web",
+				"aud": []string{"tokensmith"},
+				"exp": now.Add(5 * time.Minute).Unix(),
+			},
+		},
+		{
+			name: "missing exp",
+			claims: jwtv5.MapClaims{
+				"sub": "spiffe://cluster.local/ns/default/sa/web",
+				"aud": []string{"tokensmith"},
+			},
+			expectError: true,
+		},
+		{
+			name: "wrong audience",
+			claims: jwtv5.MapClaims{
+				"sub": "spiffe://cluster.local/ns/default/sa/web",
+				"aud": []string{"other"},
+				"exp": now.Add(5 * time.Minute).Unix(),
+			},
+			expectError: true,
+		},
+		{
+			name: "unknown trust domain",
+			claims: jwtv5.MapClaims{
+				"sub": "spiffe://unknown.example/ns/default/sa/web",
+				"aud": []string{"tokensmith"},
+				"exp": now.Add(5 * time.Minute).Unix(),
+			},
+			expectError: true,
+		},
+		{
+			name: "bundle key is for X.509-SVIDs",
+			claims: jwtv5.MapClaims{
+				"sub": "spiffe://example.org/ns/default/sa/web",
+				"aud": []string{"tokensmith"},
+				"exp": now.Add(5 * time.Minute).Unix(),
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := signer.SignClaims(tt.claims)
+			if err != nil {
+				t.Fatalf("Failed to sign token: %v", err)
+			}
+
+			identity, err := validator.Validate(ctx, token)
+			if tt.expectError {
+				if err == nil {
+					t.Fatalf("Expected error, got identity %+v", identity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validation failed: %v", err)
+			}
+			if identity.Namespace != "default" || identity.Name != "web" {
+				t.Errorf("Expected default/web, got %s/%s", identity.Namespace, identity.Name)
+			}
+			if identity.Cluster != "mesh" {
+				t.Errorf("Expected cluster mesh, got %q", identity.Cluster)
+			}
+			if identity.Kind != SPIFFEToken {
+				t.Errorf("Expected kind %s, got %s", SPIFFEToken, identity.Kind)
+			}
+			if identity.Username != "system:serviceaccount:default:web" {
+				t.Errorf("Expected username system:serviceaccount:default:web, got %s", identity.Username)
+			}
+		})
+	}
+}
+
+func TestJWKSValidator_SPIFFEBundleEndpoint(t *testing.T) {
+	signer, err := testutil.NewJWTSigner("")
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	bundle := newTrustBundle(signer)
+	bundle.RefreshHint = 300
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=3600")
+		writeJSON(w, bundle)
+	}))
+	defer server.Close()
+
+	cfg := &config.ClustersConfig{
+		Clusters: []config.ClusterConfig{
+			{
+				Name:        "mesh",
+				Type:        config.ClusterTypeSPIFFE,
+				TrustDomain: "cluster.local",
+				Audiences:   []string{"tokensmith"},
+				JWKSURI:     server.URL,
+			},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Invalid configuration: %v", err)
+	}
+	validator := NewJWKSValidator(cfg)
+
+	token, err := signer.SignClaims(jwtv5.MapClaims{
+		"sub": "spiffe://cluster.local/ns/default/sa/web",
+		"aud": []string{"tokensmith"},
+		"exp": time.Now().Add(5 * time.Minute).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	if _, err := validator.Validate(context.Background(), token); err != nil {
+		t.Fatalf("Validation failed: %v", err)
+	}
+
+	// The refresh hint takes precedence over the Cache-Control header
+	cached := validator.cache[server.URL]
+	if ttl := cached.expiresAt.Sub(cached.fetchedAt); ttl != 5*time.Minute {
+		t.Errorf("Expected cache lifetime of 5m from the refresh hint, got %v", ttl)
+	}
+}
+
+func TestClusterConfig_ValidateSPIFFE(t *testing.T) {
+	valid := func() config.ClusterConfig {
+		return config.ClusterConfig{
+			Name:        "mesh",
+			Type:        config.ClusterTypeSPIFFE,
+			TrustDomain: "cluster.local",
+			Audiences:   []string{"tokensmith"},
+			JWKSFile:    "/etc/tokensmith/bundle.json",
+		}
+	}
+
+	tests := []struct {
+		name        string
+		modify      func(c *config.ClusterConfig)
+		expectError bool
+	}{
+		{
+			name:   "valid",
+			modify: func(c *config.ClusterConfig) {},
+		},
+		{
+			name:   "custom paths",
+			modify: func(c *config.ClusterConfig) { c.SPIFFEPaths = []string{"/k8s/{namespace}/{name}"} },
+		},
+		{
+			name:        "missing trust domain",
+			modify:      func(c *config.ClusterConfig) { c.TrustDomain = "" },
+			expectError: true,
+		},
+		{
+			name:        "invalid trust domain",
+			modify:      func(c *config.ClusterConfig) { c.TrustDomain = "Cluster.Local" },
+			expectError: true,
+		},
+		{
+			name:        "path without name",
+			modify:      func(c *config.ClusterConfig) { c.SPIFFEPaths = []string{"/ns/{namespace}"} },
+			expectError: true,
+		},
+		{
+			name:        "partial segment placeholder",
+			modify:      func(c *config.ClusterConfig) { c.SPIFFEPaths = []string{"/ns/{namespace}/sa-{name}"} },
+			expectError: true,
+		},
+		{
+			name: "discovery",
+			modify: func(c *config.ClusterConfig) {
+				c.JWKSFile = ""
+				c.Issuer = "https://spire.example.com"
+				c.Discovery = true
+			},
+			expectError: true,
+		},
+		{
+			name:        "trust domain on kubernetes cluster",
+			modify:      func(c *config.ClusterConfig) { c.Type = ""; c.Issuer = "https://kubernetes.default.svc" },
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := valid()
+			tt.modify(&cluster)
+			err := cluster.Validate()
+			if tt.expectError && err == nil {
+				t.Error("Expected validation error")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package token
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/holos-run/tokensmith/internal/config"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Annotations on management cluster service accounts.
+const (
+	// TrustedSubjectsAnnotation lists the workload identities allowed to
+	// exchange for the service account as "<cluster>/<namespace>/<name>"
+	// entries separated by commas or whitespace. Each part is a glob, e.g.
+	// "tenant-a-prod/ci/*".
+	TrustedSubjectsAnnotation = "tokensmith.holos.run/trusted-subjects"
+
+	// TokenTTLAnnotation overrides the lifetime of tokens issued for the
+	// service account, e.g. "15m".
+	TokenTTLAnnotation = "tokensmith.holos.run/token-ttl"
+
+	// AudiencesAnnotation overrides the audiences of tokens issued for the
+	// service account as a comma separated list.
+	AudiencesAnnotation = "tokensmith.holos.run/audiences"
+)
+
+// checkTrustedSubjects verifies that a management service account trusts a
+// workload identity in its TrustedSubjectsAnnotation.
+func checkTrustedSubjects(sa *corev1.ServiceAccount, identity *ServiceAccountIdentity) error {
+	value, ok := sa.Annotations[TrustedSubjectsAnnotation]
+	if !ok {
+		return fmt.Errorf("%w: service account %s/%s has no %s annotation",
+			ErrExchangeDenied, sa.Namespace, sa.Name, TrustedSubjectsAnnotation)
+	}
+
+	for _, entry := range splitAnnotationList(value) {
+		if matchTrustedSubject(entry, identity) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: service account %s/%s does not trust %s/%s/%s",
+		ErrExchangeDenied, sa.Namespace, sa.Name, identity.Cluster, identity.Namespace, identity.Name)
+}
+
+// matchTrustedSubject reports whether a "<cluster>/<namespace>/<name>" entry
+// matches an identity. Malformed entries never match.
+func matchTrustedSubject(entry string, identity *ServiceAccountIdentity) bool {
+	parts := strings.Split(entry, "/")
+	if len(parts) != 3 {
+		return false
+	}
+
+	for i, value := range []string{identity.Cluster, identity.Namespace, identity.Name} {
+		matched, err := path.Match(parts[i], value)
+		if err != nil || !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// tokenOverrides returns the audiences and expiration of tokens for a
+// management service account, applying its annotation overrides to the
+// defaults.
+func tokenOverrides(sa *corev1.ServiceAccount, audiences []string, expirationSeconds int64) ([]string, int64, error) {
+	if value, ok := sa.Annotations[AudiencesAnnotation]; ok {
+		audiences = splitAnnotationList(value)
+		if len(audiences) == 0 {
+			return nil, 0, fmt.Errorf("service account %s/%s: %s annotation is empty",
+				sa.Namespace, sa.Name, AudiencesAnnotation)
+		}
+	}
+
+	if value, ok := sa.Annotations[TokenTTLAnnotation]; ok {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, 0, fmt.Errorf("service account %s/%s: invalid %s annotation: %w",
+				sa.Namespace, sa.Name, TokenTTLAnnotation, err)
+		}
+		if ttl < config.MinTokenExpiration {
+			return nil, 0, fmt.Errorf("service account %s/%s: %s annotation must be at least %s",
+				sa.Namespace, sa.Name, TokenTTLAnnotation, config.MinTokenExpiration)
+		}
+		expirationSeconds = int64(ttl / time.Second)
+	}
+
+	return audiences, expirationSeconds, nil
+}
+
+// splitAnnotationList splits an annotation value on commas and whitespace.
+func splitAnnotationList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
@@ -0,0 +1,217 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/holos-run/tokensmith/internal/config"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newAnnotatedServiceAccount returns a management service account with the
+// given annotations.
+func newAnnotatedServiceAccount(annotations map[string]string) *corev1.ServiceAccount {
+	return &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "deployer",
+			Namespace:   "tenant-a",
+			Annotations: annotations,
+		},
+	}
+}
+
+func TestCheckTrustedSubjects(t *testing.T) {
+	identity := &ServiceAccountIdentity{Cluster: "tenant-a-prod", Namespace: "ci", Name: "deploy"}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expectError bool
+	}{
+		{
+			name:        "exact subject",
+			annotations: map[string]string{TrustedSubjectsAnnotation: "tenant-a-prod/ci/deploy"},
+		},
+		{
+			name:        "glob in list",
+			annotations: map[string]string{TrustedSubjectsAnnotation: "tenant-a-dev/ci/deploy, tenant-a-*/ci/*"},
+		},
+		{
+			name:        "newline separated",
+			annotations: map[string]string{TrustedSubjectsAnnotation: "tenant-b/ci/deploy\ntenant-a-prod/ci/deploy\n"},
+		},
+		{
+			name:        "other cluster",
+			annotations: map[string]string{TrustedSubjectsAnnotation: "tenant-b/ci/deploy"},
+			expectError: true,
+		},
+		{
+			name:        "glob does not span parts",
+			annotations: map[string]string{TrustedSubjectsAnnotation: "*/deploy"},
+			expectError: true,
+		},
+		{
+			name:        "missing annotation",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkTrustedSubjects(newAnnotatedServiceAccount(tt.annotations), identity)
+			if tt.expectError {
+				if !errors.Is(err, ErrExchangeDenied) {
+					t.Errorf("Expected ErrExchangeDenied, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestTokenOverrides(t *testing.T) {
+	defaults := []string{"https://kubernetes.default.svc"}
+
+	tests := []struct {
+		name               string
+		annotations        map[string]string
+		expectError        bool
+		expectedAudiences  []string
+		expectedExpiration int64
+	}{
+		{
+			name:               "defaults",
+			expectedAudiences:  defaults,
+			expectedExpiration: 3600,
+		},
+		{
+			name: "overrides",
+			annotations: map[string]string{
+				AudiencesAnnotation: "vault, https://kubernetes.default.svc",
+				TokenTTLAnnotation:  "15m",
+			},
+			expectedAudiences:  []string{"vault", "https://kubernetes.default.svc"},
+			expectedExpiration: 900,
+		},
+		{
+			name:        "ttl below TokenRequest minimum",
+			annotations: map[string]string{TokenTTLAnnotation: "5m"},
+			expectError: true,
+		},
+		{
+			name:        "invalid ttl",
+			annotations: map[string]string{TokenTTLAnnotation: "an hour"},
+			expectError: true,
+		},
+		{
+			name:        "empty audiences",
+			annotations: map[string]string{AudiencesAnnotation: " , "},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audiences, expiration, err := tokenOverrides(newAnnotatedServiceAccount(tt.annotations), defaults, 3600)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !slices.Equal(audiences, tt.expectedAudiences) {
+				t.Errorf("Expected audiences %v, got %v", tt.expectedAudiences, audiences)
+			}
+			if expiration != tt.expectedExpiration {
+				t.Errorf("Expected expiration %d, got %d", tt.expectedExpiration, expiration)
+			}
+		})
+	}
+}
+
+func TestExchanger_RequireTrustedSubjects(t *testing.T) {
+	ctx := context.Background()
+
+	managementClient := fake.NewSimpleClientset(newAnnotatedServiceAccount(map[string]string{
+		TrustedSubjectsAnnotation: "tenant-a-prod/tenant-a/deployer",
+		AudiencesAnnotation:       "vault",
+		TokenTTLAnnotation:        "20m",
+	}))
+
+	var spec authenticationv1.TokenRequestSpec
+	managementClient.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+		tokenReq := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenRequest)
+		spec = tokenReq.Spec
+		tokenReq.Status = authenticationv1.TokenRequestStatus{
+			Token:               "management-token",
+			ExpirationTimestamp: metav1.Time{Time: time.Now().Add(20 * time.Minute)},
+		}
+		return true, tokenReq, nil
+	})
+
+	exchanger := NewExchanger(managementClient, ExchangeConfig{RequireTrustedSubjects: true})
+
+	trusted := &ServiceAccountIdentity{Cluster: "tenant-a-prod", Namespace: "tenant-a", Name: "deployer", UID: "uid-1"}
+	if _, err := exchanger.Exchange(ctx, trusted); err != nil {
+		t.Fatalf("Exchange failed: %v", err)
+	}
+	if !slices.Equal(spec.Audiences, []string{"vault"}) {
+		t.Errorf("Expected audiences [vault], got %v", spec.Audiences)
+	}
+	if spec.ExpirationSeconds == nil || *spec.ExpirationSeconds != 1200 {
+		t.Errorf("Expected expiration of 1200 seconds, got %v", spec.ExpirationSeconds)
+	}
+
+	untrusted := &ServiceAccountIdentity{Cluster: "tenant-b", Namespace: "tenant-a", Name: "deployer", UID: "uid-2"}
+	if _, err := exchanger.Exchange(ctx, untrusted); !errors.Is(err, ErrExchangeDenied) {
+		t.Errorf("Expected ErrExchangeDenied, got %v", err)
+	}
+}
+
+func TestExchanger_AudienceAcceptedByWorkloadCluster(t *testing.T) {
+	managementClient := fake.NewSimpleClientset(newAnnotatedServiceAccount(map[string]string{
+		AudiencesAnnotation: "tokensmith",
+	}))
+	createTokenCalls := 0
+	managementClient.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() == "token" {
+			createTokenCalls++
+		}
+		return false, nil, nil
+	})
+
+	exchanger := NewExchanger(managementClient, ExchangeConfig{
+		Clusters: &config.ClustersConfig{
+			Clusters: []config.ClusterConfig{
+				{Name: "tenant-a-prod", Audiences: []string{"tokensmith"}, ManagementNamespaces: []string{"tenant-a"}},
+			},
+		},
+	})
+
+	// A token for the annotated audience would be accepted by the workload
+	// cluster and could be exchanged again
+	identity := &ServiceAccountIdentity{Cluster: "tenant-a-prod", Namespace: "tenant-a", Name: "deployer", UID: "uid-1"}
+	if _, err := exchanger.Exchange(context.Background(), identity); !errors.Is(err, ErrExchangeDenied) {
+		t.Errorf("Expected ErrExchangeDenied, got %v", err)
+	}
+	if createTokenCalls != 0 {
+		t.Errorf("Expected no CreateToken calls, got %d", createTokenCalls)
+	}
+}
@@ -2,8 +2,10 @@ package token
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	authenticationv1 "k8s.io/api/authentication/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -21,8 +23,41 @@ type ServiceAccountIdentity struct {
 	// UID is the unique identifier of the service account.
 	UID string
 
+	// Cluster is the name of the workload cluster configuration the token was
+	// validated with. It is empty in single cluster TokenReview mode.
+	Cluster string
+
 	// Username is the full username (e.g., "system:serviceaccount:namespace:name").
 	Username string
+
+	// Kind classifies the token the identity was validated from.
+	Kind TokenKind
+
+	// TokenID is the jti claim of the token, if any.
+	TokenID string
+
+	// ReplaySource is the source the token was first seen from. It is set
+	// when replay protection flags the token as presented from a different
+	// source.
+	ReplaySource *Source
+
+	// Pod is the pod the token is bound to, if any.
+	Pod *BoundObject
+
+	// Node is the node the token is bound to, if any.
+	Node *BoundObject
+
+	// Secret is the secret the token is bound to, if any.
+	Secret *BoundObject
+}
+
+// BoundObject identifies a Kubernetes object a service account token is bound to.
+type BoundObject struct {
+	// Name is the name of the object.
+	Name string
+
+	// UID is the unique identifier of the object.
+	UID string
 }
 
 // TokenValidator is the interface for validating tokens.
@@ -33,23 +68,116 @@ type TokenValidator interface {
 // Validator validates tokens using the Kubernetes TokenReview API.
 // This is the legacy method that makes network calls to the workload cluster.
 type Validator struct {
-	client kubernetes.Interface
+	client    kubernetes.Interface
+	audiences []string
+
+	// legacyMaxAge is the maximum age of accepted legacy tokens.
+	// Zero rejects legacy tokens.
+	legacyMaxAge time.Duration
+
+	// managementAudiences are rejected in authenticated tokens to prevent
+	// exchange loops. Empty disables the check.
+	managementAudiences []string
+
+	// cache holds recent validation results. Nil disables caching.
+	cache *reviewCache
 }
 
 // NewValidator creates a new token validator using the TokenReview API.
-func NewValidator(client kubernetes.Interface) *Validator {
+// If audiences is non-empty, the TokenReview requests that the token be
+// valid for at least one of them.
+func NewValidator(client kubernetes.Interface, audiences []string) *Validator {
 	return &Validator{
-		client: client,
+		client:    client,
+		audiences: audiences,
+	}
+}
+
+// AllowLegacyTokens accepts legacy Secret-based service account tokens whose
+// Secret was created within maxAge. By default legacy tokens are rejected.
+func (v *Validator) AllowLegacyTokens(maxAge time.Duration) {
+	v.legacyMaxAge = maxAge
+}
+
+// RejectManagementAudiences rejects tokens carrying any of the given
+// audiences, like the JWKS validator does, so tokens issued by the exchange
+// can't be exchanged again. Pass every audience exchanged tokens may carry,
+// including those of exchange profiles.
+func (v *Validator) RejectManagementAudiences(audiences []string) {
+	v.managementAudiences = audiences
+}
+
+// EnableCache caches validation results so repeated checks of the same token
+// don't each create a TokenReview. Successful results are cached until the
+// token expires, capped at maxTTL. Tokens the TokenReview rejected are cached
+// for negativeTTL; zero disables negative caching. Failures to review a token,
+// such as API server errors, are never cached.
+func (v *Validator) EnableCache(maxTTL, negativeTTL time.Duration) {
+	if maxTTL <= 0 && negativeTTL <= 0 {
+		v.cache = nil
+		return
 	}
+	v.cache = newReviewCache(maxTTL, negativeTTL)
 }
 
 // Validate validates a bearer token using the Kubernetes TokenReview API.
 // It returns the service account identity if the token is valid.
 func (v *Validator) Validate(ctx context.Context, bearerToken string) (*ServiceAccountIdentity, error) {
+	if v.cache == nil {
+		return v.review(ctx, bearerToken)
+	}
+
+	now := time.Now()
+	if cached, ok := v.cache.get(bearerToken, now); ok {
+		if cached.err != nil {
+			return nil, cached.err
+		}
+		identity := *cached.identity
+		return &identity, nil
+	}
+
+	identity, err := v.review(ctx, bearerToken)
+
+	// Only cache definitive rejections, so a transient API server failure
+	// doesn't reject valid tokens for the negative TTL
+	var rejection *rejectionError
+	if err != nil && !errors.As(err, &rejection) {
+		return nil, err
+	}
+
+	var expiry *time.Time
+	if claims, _, parseErr := parseUnverifiedClaims(bearerToken); parseErr == nil && claims.Expiry != nil {
+		exp := claims.Expiry.Time()
+		expiry = &exp
+	}
+	v.cache.set(bearerToken, identity, err, expiry, now)
+
+	return identity, err
+}
+
+// rejectionError marks a definitive rejection of a token, as opposed to a
+// failure to review it.
+type rejectionError struct {
+	err error
+}
+
+func (e *rejectionError) Error() string { return e.err.Error() }
+
+func (e *rejectionError) Unwrap() error { return e.err }
+
+// rejected marks err as a definitive rejection of a token.
+func rejected(err error) error {
+	return &rejectionError{err: err}
+}
+
+// review validates a bearer token by creating a TokenReview. Errors for
+// tokens the TokenReview rejected are marked with rejected.
+func (v *Validator) review(ctx context.Context, bearerToken string) (*ServiceAccountIdentity, error) {
 	// Create TokenReview request
 	tokenReview := &authenticationv1.TokenReview{
 		Spec: authenticationv1.TokenReviewSpec{
-			Token: bearerToken,
+			Token:     bearerToken,
+			Audiences: v.audiences,
 		},
 	}
 
@@ -62,21 +190,76 @@ func (v *Validator) Validate(ctx context.Context, bearerToken string) (*ServiceA
 	// Check if token is authenticated
 	if !result.Status.Authenticated {
 		if result.Status.Error != "" {
-			return nil, fmt.Errorf("token validation failed: %s", result.Status.Error)
+			return nil, rejected(fmt.Errorf("token validation failed: %s", result.Status.Error))
 		}
-		return nil, fmt.Errorf("token is not authenticated")
+		return nil, rejected(fmt.Errorf("token is not authenticated"))
+	}
+
+	// The authenticator returns the audiences compatible with both the
+	// request and the token; an empty intersection means no match.
+	if len(v.audiences) > 0 && len(result.Status.Audiences) == 0 {
+		return nil, rejected(fmt.Errorf("token audiences do not match %v", v.audiences))
 	}
 
 	// Extract and parse service account identity
 	username := result.Status.User.Username
 	identity, err := parseServiceAccountIdentity(username, result.Status.User.UID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse service account identity: %w", err)
+		return nil, rejected(fmt.Errorf("failed to parse service account identity: %w", err))
+	}
+
+	// Reject tokens minted for the management cluster to prevent exchange
+	// loops. The TokenReview authenticated the token, so its claims can be
+	// trusted.
+	if len(v.managementAudiences) > 0 {
+		claims, _, err := parseUnverifiedClaims(bearerToken)
+		if err != nil {
+			return nil, rejected(fmt.Errorf("failed to parse token claims: %w", err))
+		}
+		for _, aud := range v.managementAudiences {
+			if claims.Audience.Contains(aud) {
+				return nil, rejected(fmt.Errorf("token carries management cluster audience %q", aud))
+			}
+		}
+	}
+
+	extra := result.Status.User.Extra
+	identity.Pod = boundObjectFromExtra(extra, "pod")
+	identity.Node = boundObjectFromExtra(extra, "node")
+
+	// The TokenReview authenticated the token, so its claims can be trusted
+	// to tell legacy tokens apart
+	kind, k8sClaims := classifyUnverifiedToken(bearerToken)
+	identity.Kind = kind
+	if kind == LegacyToken {
+		if v.legacyMaxAge == 0 {
+			return nil, rejected(ErrLegacyToken)
+		}
+		identity.Secret = extractBoundObject(k8sClaims, "secret")
+		if err := checkLegacyTokenAge(ctx, v.client, identity, v.legacyMaxAge, time.Now()); err != nil {
+			return nil, err
+		}
 	}
 
 	return identity, nil
 }
 
+// boundObjectFromExtra extracts a bound object from the TokenReview user extra
+// fields, e.g. "authentication.kubernetes.io/pod-name" and
+// "authentication.kubernetes.io/pod-uid". Returns nil if the name is absent.
+func boundObjectFromExtra(extra map[string]authenticationv1.ExtraValue, kind string) *BoundObject {
+	name := extra["authentication.kubernetes.io/"+kind+"-name"]
+	if len(name) == 0 || name[0] == "" {
+		return nil
+	}
+
+	obj := &BoundObject{Name: name[0]}
+	if uid := extra["authentication.kubernetes.io/"+kind+"-uid"]; len(uid) > 0 {
+		obj.UID = uid[0]
+	}
+	return obj
+}
+
 // parseServiceAccountIdentity parses a Kubernetes service account username
 // into its component parts.
 //
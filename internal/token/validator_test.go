@@ -1,7 +1,16 @@
 package token
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
+
+	"github.com/holos-run/tokensmith/internal/testutil"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 func TestParseServiceAccountIdentity(t *testing.T) {
@@ -107,3 +116,215 @@ func TestParseServiceAccountIdentity(t *testing.T) {
 		})
 	}
 }
+
+func TestValidator_Audiences(t *testing.T) {
+	ctx := context.Background()
+	audiences := []string{"tokensmith"}
+
+	tests := []struct {
+		name           string
+		statusAudience []string
+		expectError    bool
+	}{
+		{
+			name:           "audience matches",
+			statusAudience: []string{"tokensmith"},
+			expectError:    false,
+		},
+		{
+			name:           "no compatible audience",
+			statusAudience: nil,
+			expectError:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requested []string
+			client := fake.NewSimpleClientset()
+			client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				tr := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+				requested = tr.Spec.Audiences
+				tr.Status = authenticationv1.TokenReviewStatus{
+					Authenticated: true,
+					Audiences:     tt.statusAudience,
+					User: authenticationv1.UserInfo{
+						Username: "system:serviceaccount:default:my-sa",
+						UID:      "12345",
+					},
+				}
+				return true, tr, nil
+			})
+
+			_, err := NewValidator(client, audiences).Validate(ctx, "token")
+
+			if len(requested) != 1 || requested[0] != "tokensmith" {
+				t.Errorf("expected TokenReview audiences %v, got %v", audiences, requested)
+			}
+
+			if tt.expectError && err == nil {
+				t.Errorf("expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidator_ManagementAudiences(t *testing.T) {
+	ctx := context.Background()
+
+	signer, err := testutil.NewJWTSigner("https://workload.example.com")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		audiences   []string
+		expectError bool
+	}{
+		{
+			name:      "workload audience",
+			audiences: []string{"tokensmith"},
+		},
+		{
+			name:        "management audience",
+			audiences:   []string{"tokensmith", "https://kubernetes.default.svc"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bearerToken, err := signer.GenerateToken("default", "my-sa", "12345", tt.audiences, time.Now().Add(time.Hour))
+			if err != nil {
+				t.Fatalf("failed to generate token: %v", err)
+			}
+
+			client := fake.NewSimpleClientset()
+			client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				tr := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+				tr.Status = authenticationv1.TokenReviewStatus{
+					Authenticated: true,
+					Audiences:     []string{"tokensmith"},
+					User: authenticationv1.UserInfo{
+						Username: "system:serviceaccount:default:my-sa",
+						UID:      "12345",
+					},
+				}
+				return true, tr, nil
+			})
+
+			validator := NewValidator(client, []string{"tokensmith"})
+			validator.RejectManagementAudiences([]string{"https://kubernetes.default.svc"})
+			_, err = validator.Validate(ctx, bearerToken)
+
+			if tt.expectError && err == nil {
+				t.Errorf("expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestBoundObjectFromExtra(t *testing.T) {
+	extra := map[string]authenticationv1.ExtraValue{
+		"authentication.kubernetes.io/pod-name":  {"my-pod"},
+		"authentication.kubernetes.io/pod-uid":   {"pod-uid"},
+		"authentication.kubernetes.io/node-name": {"node-1"},
+	}
+
+	pod := boundObjectFromExtra(extra, "pod")
+	if pod == nil || pod.Name != "my-pod" || pod.UID != "pod-uid" {
+		t.Errorf("pod mismatch: got %+v", pod)
+	}
+
+	node := boundObjectFromExtra(extra, "node")
+	if node == nil || node.Name != "node-1" || node.UID != "" {
+		t.Errorf("node mismatch: got %+v", node)
+	}
+
+	if secret := boundObjectFromExtra(extra, "secret"); secret != nil {
+		t.Errorf("expected no secret binding, got %+v", secret)
+	}
+}
+
+func TestValidator_Cache(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		authenticated bool
+		reviewErr     error
+		negativeTTL   time.Duration
+		expectReviews int
+	}{
+		{
+			name:          "valid token",
+			authenticated: true,
+			expectReviews: 1,
+		},
+		{
+			name:          "invalid token cached briefly",
+			negativeTTL:   time.Minute,
+			expectReviews: 1,
+		},
+		{
+			name:          "invalid token without negative caching",
+			expectReviews: 3,
+		},
+		{
+			name:          "API server error not cached",
+			reviewErr:     errors.New("the server is currently unable to handle the request"),
+			negativeTTL:   time.Minute,
+			expectReviews: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reviews := 0
+			client := fake.NewSimpleClientset()
+			client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				reviews++
+				if tt.reviewErr != nil {
+					return true, nil, tt.reviewErr
+				}
+				tr := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+				tr.Status = authenticationv1.TokenReviewStatus{
+					Authenticated: tt.authenticated,
+					User: authenticationv1.UserInfo{
+						Username: "system:serviceaccount:default:my-sa",
+						UID:      "12345",
+					},
+				}
+				return true, tr, nil
+			})
+
+			validator := NewValidator(client, nil)
+			validator.EnableCache(time.Minute, tt.negativeTTL)
+
+			for range 3 {
+				identity, err := validator.Validate(ctx, "token")
+				if tt.authenticated {
+					if err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+					if identity.Name != "my-sa" {
+						t.Errorf("expected name 'my-sa', got %q", identity.Name)
+					}
+				} else if err == nil {
+					t.Fatal("expected error but got none")
+				}
+			}
+
+			if reviews != tt.expectReviews {
+				t.Errorf("expected %d TokenReviews, got %d", tt.expectReviews, reviews)
+			}
+		})
+	}
+}
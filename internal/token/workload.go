@@ -0,0 +1,83 @@
+package token
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// workloadTransport fetches JWKS and discovery documents through a workload
+// cluster API server using the cluster's kubeconfig credentials.
+type workloadTransport struct {
+	client *http.Client
+	host   *url.URL
+}
+
+// SetWorkloadRESTConfig configures authenticated access to the named workload
+// cluster's API server. JWKS and discovery documents for the cluster are then
+// fetched through the API server, and the cluster's workload client is set as
+// with SetWorkloadClient.
+func (v *JWKSValidator) SetWorkloadRESTConfig(cluster string, cfg *rest.Config) error {
+	cfg = rest.CopyConfig(cfg)
+	if cfg.Timeout == 0 {
+		cfg.Timeout = fetchTimeout
+	}
+
+	httpClient, err := rest.HTTPClientFor(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP client for cluster %s: %w", cluster, err)
+	}
+
+	host, _, err := rest.DefaultServerUrlFor(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to determine API server URL for cluster %s: %w", cluster, err)
+	}
+
+	client, err := kubernetes.NewForConfigAndClient(cfg, httpClient)
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client for cluster %s: %w", cluster, err)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.workloadTransports[cluster] = &workloadTransport{
+		client: httpClient,
+		host:   host,
+	}
+	v.workloadClients[cluster] = client
+
+	return nil
+}
+
+// transportFor returns the workload transport for a cluster, or nil if the
+// cluster has no workload API server access configured.
+func (v *JWKSValidator) transportFor(cluster string) *workloadTransport {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.workloadTransports[cluster]
+}
+
+// resolveURL returns uri as served by the cluster's API server if workload
+// access is configured. Relative URIs are resolved against the API server,
+// and absolute URIs keep their path and query but use the API server host,
+// since the issuer host is often not reachable from outside the cluster.
+// The path is joined to any path of the API server URL, such as the cluster
+// prefix of a proxy like https://rancher.example.com/k8s/clusters/c-xxx.
+func (v *JWKSValidator) resolveURL(cluster, uri string) (string, error) {
+	t := v.transportFor(cluster)
+	if t == nil {
+		return uri, nil
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %q: %w", uri, err)
+	}
+
+	resolved := t.host.JoinPath(u.EscapedPath())
+	resolved.RawQuery = u.RawQuery
+	return resolved.String(), nil
+}
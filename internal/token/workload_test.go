@@ -0,0 +1,262 @@
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/holos-run/tokensmith/internal/config"
+	"github.com/holos-run/tokensmith/internal/testutil"
+	"k8s.io/client-go/rest"
+)
+
+// newWorkloadAPIServer starts a test API server that serves the OIDC
+// discovery document and JWKS only to requests with the expected bearer token.
+func newWorkloadAPIServer(t *testing.T, issuer string, signer *testutil.JWTSigner) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, discoveryDocument{
+			Issuer:      issuer,
+			JWKSURI:     issuer + "/openid/v1/jwks",
+			SigningAlgs: []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/openid/v1/jwks", func(w http.ResponseWriter, r *http.Request) {
+		// The API server serves keys with the JWK set media type
+		w.Header().Set("Content-Type", "application/jwk-set+json")
+		_ = json.NewEncoder(w).Encode(createJWKS(signer.PublicKey(), signer.KeyID()))
+	})
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer workload-credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestJWKSValidator_WorkloadAPIServer(t *testing.T) {
+	ctx := context.Background()
+
+	// The issuer host is not reachable; keys are only served by the API server
+	issuer := "https://kubernetes.default.svc.cluster.local"
+	signer, err := testutil.NewJWTSigner(issuer)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	server := newWorkloadAPIServer(t, issuer, signer)
+
+	restConfig := &rest.Config{
+		Host:        server.URL,
+		BearerToken: "workload-credentials",
+		TLSClientConfig: rest.TLSClientConfig{
+			CAData: certPEM(t, server),
+		},
+	}
+
+	tests := []struct {
+		name    string
+		cluster config.ClusterConfig
+	}{
+		{
+			name: "discovery through API server",
+			cluster: config.ClusterConfig{
+				Discovery: true,
+			},
+		},
+		{
+			name: "relative jwks_uri",
+			cluster: config.ClusterConfig{
+				JWKSURI: "/openid/v1/jwks",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := tt.cluster
+			cluster.Name = "cluster"
+			cluster.Issuer = issuer
+			cluster.Audiences = []string{"tokensmith"}
+			cluster.Kubeconfig = "/etc/tokensmith/cluster.kubeconfig"
+
+			cfg := &config.ClustersConfig{Clusters: []config.ClusterConfig{cluster}}
+			if err := cfg.Validate(); err != nil {
+				t.Fatalf("Invalid configuration: %v", err)
+			}
+
+			validator := NewJWKSValidator(cfg)
+			if err := validator.SetWorkloadRESTConfig("cluster", restConfig); err != nil {
+				t.Fatalf("Failed to set workload REST config: %v", err)
+			}
+
+			token, err := signer.GenerateToken("default", "my-sa", uuid.New().String(),
+				[]string{"tokensmith"}, time.Now().Add(1*time.Hour))
+			if err != nil {
+				t.Fatalf("Failed to generate token: %v", err)
+			}
+
+			identity, err := validator.Validate(ctx, token)
+			if err != nil {
+				t.Fatalf("Validation failed: %v", err)
+			}
+			if identity.Name != "my-sa" {
+				t.Errorf("Expected name 'my-sa', got %q", identity.Name)
+			}
+		})
+	}
+
+	t.Run("anonymous fetch is rejected", func(t *testing.T) {
+		cfg := &config.ClustersConfig{
+			Clusters: []config.ClusterConfig{
+				{
+					Name:      "cluster",
+					Issuer:    issuer,
+					Audiences: []string{"tokensmith"},
+					JWKSURI:   server.URL + "/openid/v1/jwks",
+					TLS: &config.TLSConfig{
+						CAFile: writeCAFile(t, server),
+					},
+				},
+			},
+		}
+		validator := NewJWKSValidator(cfg)
+
+		token, err := signer.GenerateToken("default", "my-sa", uuid.New().String(),
+			[]string{"tokensmith"}, time.Now().Add(1*time.Hour))
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+
+		if _, err := validator.Validate(ctx, token); err == nil {
+			t.Fatal("Expected validation to fail without workload credentials")
+		}
+	})
+}
+
+func TestResolveURL(t *testing.T) {
+	validator := NewJWKSValidator(&config.ClustersConfig{})
+	if err := validator.SetWorkloadRESTConfig("cluster", &rest.Config{Host: "https://10.0.0.1:6443"}); err != nil {
+		t.Fatalf("Failed to set workload REST config: %v", err)
+	}
+	if err := validator.SetWorkloadRESTConfig("proxied", &rest.Config{Host: "https://rancher.example.com/k8s/clusters/c-xxx"}); err != nil {
+		t.Fatalf("Failed to set workload REST config: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		cluster string
+		uri     string
+		// discovery resolves the discovery document of an issuer at uri
+		discovery bool
+		want      string
+	}{
+		{
+			name:    "relative path",
+			cluster: "cluster",
+			uri:     "/openid/v1/jwks",
+			want:    "https://10.0.0.1:6443/openid/v1/jwks",
+		},
+		{
+			name:    "absolute issuer URL",
+			cluster: "cluster",
+			uri:     "https://kubernetes.default.svc/openid/v1/jwks",
+			want:    "https://10.0.0.1:6443/openid/v1/jwks",
+		},
+		{
+			name:    "query is kept",
+			cluster: "cluster",
+			uri:     "https://kubernetes.default.svc/keys?format=jwks",
+			want:    "https://10.0.0.1:6443/keys?format=jwks",
+		},
+		{
+			name:    "API server path prefix",
+			cluster: "proxied",
+			uri:     "https://kubernetes.default.svc/openid/v1/jwks",
+			want:    "https://rancher.example.com/k8s/clusters/c-xxx/openid/v1/jwks",
+		},
+		{
+			name:    "relative path with API server path prefix",
+			cluster: "proxied",
+			uri:     "/.well-known/openid-configuration",
+			want:    "https://rancher.example.com/k8s/clusters/c-xxx/.well-known/openid-configuration",
+		},
+		{
+			name:      "discovery for issuer with path",
+			cluster:   "cluster",
+			uri:       "https://oidc.example.com/clusters/a",
+			discovery: true,
+			want:      "https://10.0.0.1:6443/.well-known/openid-configuration",
+		},
+		{
+			name:      "discovery with API server path prefix",
+			cluster:   "proxied",
+			uri:       "https://oidc.example.com/clusters/a",
+			discovery: true,
+			want:      "https://rancher.example.com/k8s/clusters/c-xxx/.well-known/openid-configuration",
+		},
+		{
+			name:      "discovery without workload access",
+			cluster:   "other",
+			uri:       "https://oidc.example.com/clusters/a",
+			discovery: true,
+			want:      "https://oidc.example.com/clusters/a/.well-known/openid-configuration",
+		},
+		{
+			name:    "cluster without workload access",
+			cluster: "other",
+			uri:     "https://issuer.example.com/keys",
+			want:    "https://issuer.example.com/keys",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolve := validator.resolveURL
+			if tt.discovery {
+				resolve = func(cluster, issuer string) (string, error) {
+					return validator.discoveryURI(&config.ClusterConfig{Name: cluster, Issuer: issuer})
+				}
+			}
+			got, err := resolve(tt.cluster, tt.uri)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// writeCAFile writes the certificate of a TLS test server to a temporary
+// CA file and returns its path.
+func writeCAFile(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, certPEM(t, server), 0o600); err != nil {
+		t.Fatalf("Failed to write CA file: %v", err)
+	}
+	return path
+}
+
+// certPEM returns the PEM-encoded certificate of a TLS test server.
+func certPEM(t *testing.T, server *httptest.Server) []byte {
+	t.Helper()
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: server.Certificate().Raw,
+	})
+}